@@ -0,0 +1,3 @@
+module github.com/ASHUTOSH-SWAIN-GIT/insightio/examples/batch_example
+
+go 1.25.5
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	batchURL = "http://localhost:8080/v1/events"
+	apiKey   = "test-api-key-123" // Must match a key in INSIGHTIO_API_KEY
+)
+
+// Event mirrors one entry of the "events" array accepted by /v1/events.
+type Event struct {
+	Id        string            `json:"id,omitempty"`
+	Type      string            `json:"type"`
+	UserId    string            `json:"user_id,omitempty"`
+	Value     float64           `json:"value,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+}
+
+// EventError is the result of one event the backend did not accept,
+// identified by its index in the request.
+type EventError struct {
+	Index     int    `json:"index"`
+	Reason    string `json:"reason"`
+	Retryable bool   `json:"retryable"`
+}
+
+// BatchResponse is the body returned by /v1/events, with HTTP 202 when
+// every event was accepted and 207 when some were rejected.
+type BatchResponse struct {
+	Accepted   int          `json:"accepted"`
+	Rejected   int          `json:"rejected"`
+	Duplicates int          `json:"duplicates"`
+	Filtered   int          `json:"filtered"`
+	Errors     []EventError `json:"errors"`
+}
+
+// sendBatch posts events in one request. The idempotency key lets the
+// backend recognize events it already accepted when the same batch is sent
+// again.
+func sendBatch(events []Event, idempotencyKey string) (*BatchResponse, error) {
+	body, err := json.Marshal(map[string]any{"events": events})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, batchURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var out BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func main() {
+	log.Println("=== InsightIO batch example ===")
+	log.Println("Make sure the API server is running on http://localhost:8080")
+
+	signedUp := time.Now().Add(-time.Minute)
+	events := []Event{
+		{Type: "page_view", UserId: "user123", Metadata: map[string]string{"page": "home"}},
+		{Type: "signup", UserId: "user123", Timestamp: &signedUp},
+		{Type: "purchase", UserId: "user123", Value: 99.99, Metadata: map[string]string{"currency": "USD"}},
+		{UserId: "user123"}, // no type, so rejected
+	}
+
+	key := fmt.Sprintf("batch-%d", time.Now().UnixNano())
+	resp, err := sendBatch(events, key)
+	if err != nil {
+		log.Fatalf("Failed to send batch: %v", err)
+	}
+	log.Printf("Accepted %d, rejected %d, duplicates %d, filtered %d",
+		resp.Accepted, resp.Rejected, resp.Duplicates, resp.Filtered)
+
+	// Errors point back into the request by index; only retryable ones are
+	// worth sending again
+	var retry []Event
+	for _, e := range resp.Errors {
+		log.Printf("  event %d (%s): %s (retryable: %v)", e.Index, events[e.Index].Type, e.Reason, e.Retryable)
+		if e.Retryable {
+			// keep the id the gateway derived from the key and index, so
+			// the event is not counted twice if it was accepted after all
+			event := events[e.Index]
+			if event.Id == "" {
+				event.Id = fmt.Sprintf("%s/%d", key, e.Index)
+			}
+			retry = append(retry, event)
+		}
+	}
+	if len(retry) > 0 {
+		resp, err := sendBatch(retry, key+"-retry")
+		if err != nil {
+			log.Fatalf("Failed to retry: %v", err)
+		}
+		log.Printf("Retried %d event(s): %d accepted", len(retry), resp.Accepted)
+	}
+
+	// Sending the same batch with the same key again is safe: accepted
+	// events come back as duplicates and are not counted twice
+	resp, err = sendBatch(events, key)
+	if err != nil {
+		log.Fatalf("Failed to resend batch: %v", err)
+	}
+	log.Printf("Resent the batch: %d of %d accepted events were duplicates", resp.Duplicates, resp.Accepted)
+}
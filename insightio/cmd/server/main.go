@@ -1,9 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -20,33 +31,145 @@ func main() {
 	// Load configuration from environment variables
 	cfg := config.Load()
 
-	// Create metric store with configured window size
-	store := store.NewMetricStore(cfg.MetricsWindow)
+	// Create metric store with configured window size, restoring the last
+	// snapshot if there is one
+	funnels, err := store.ReadFunnels(cfg.FunnelsPath)
+	if err != nil {
+		log.Fatalf("Failed to read funnels: %v", err)
+	}
+	storeOpts := store.Options{
+		Window:           time.Duration(cfg.MetricsWindow) * time.Second,
+		Resolution:       time.Duration(cfg.MetricsResolution) * time.Millisecond,
+		SketchResolution: time.Duration(cfg.SketchResolution) * time.Second,
+		Retention:        time.Duration(cfg.MetricsRetention) * time.Second,
+		FutureSkew:       time.Duration(cfg.FutureSkew) * time.Second,
+		MaxEventTypes:    cfg.MaxEventTypes,
+
+		Dimensions:           cfg.Dimensions,
+		DimensionCardinality: cfg.DimensionCardinality,
+
+		Funnels:     funnels,
+		FunnelUsers: cfg.FunnelUsers,
+
+		SessionGap:   time.Duration(cfg.SessionGap) * time.Second,
+		SessionUsers: cfg.SessionUsers,
+	}
+	var metricStore *store.MetricStore
+	var snapshotSeq uint64
+	snap, err := store.ReadSnapshot(cfg.SnapshotPath)
+	switch {
+	case err == nil:
+		metricStore = store.NewMetricStoreFromSnapshot(snap, storeOpts)
+		snapshotSeq = snap.WALSeq
+		log.Printf("Restored metric store from snapshot %s (taken %s)", cfg.SnapshotPath, snap.TakenAt.Format(time.RFC3339))
+	case errors.Is(err, os.ErrNotExist):
+		metricStore = store.NewMetricStoreWithOptions(storeOpts)
+	default:
+		log.Fatalf("Failed to read snapshot %s: %v", cfg.SnapshotPath, err)
+	}
+
+	// Queue used to send events from the grpc service to the workers
+	if cfg.PartitionBy != "" && cfg.PartitionBy != "user_id" {
+		log.Fatalf("Unknown partition key %q, only user_id is supported", cfg.PartitionBy)
+	}
+	// funnels and sessions need each user's events in order, which several
+	// workers only keep when events are partitioned by user
+	if cfg.Workers > 1 && cfg.PartitionBy == "" && (len(funnels) > 0 || cfg.SessionGap > 0) {
+		log.Printf("Funnels or sessions are enabled with %d workers, partitioning events by user_id", cfg.Workers)
+		cfg.PartitionBy = "user_id"
+	}
+	dedup := ingest.NewDeduper(ingest.DedupConfig{
+		TTL:           time.Duration(cfg.DedupTTL) * time.Second,
+		MaxIDs:        cfg.DedupMaxIDs,
+		BloomCapacity: cfg.DedupBloomCapacity,
+	})
+	queue, err := ingest.NewQueue(ingest.QueueConfig{
+		Size:            cfg.QueueSize,
+		Policy:          ingest.OverloadPolicy(cfg.OverloadPolicy),
+		Partitions:      cfg.Workers,
+		PartitionByUser: cfg.PartitionBy == "user_id",
+		Dedup:           dedup,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create ingest queue: %v", err)
+	}
 
-	eventChan := make(chan *pb.Event, 1000) // channel use to send events from the grpc service to worker
+	// Client timestamps outside this range are clamped or rejected
+	clock, err := ingest.NewEventClock(ingest.EventTimeConfig{
+		AllowedLateness: time.Duration(cfg.AllowedLateness) * time.Second,
+		FutureSkew:      time.Duration(cfg.FutureSkew) * time.Second,
+		Policy:          ingest.LatePolicy(cfg.LateEventPolicy),
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure event time: %v", err)
+	}
 
-	// Start worker that processes events and updates the store
-	worker := ingest.NewWorker(eventChan, store)
+	// Processors that enrich, filter and rename incoming events
+	pipeline, err := ingest.LoadPipeline(cfg.PipelinePath)
+	if err != nil {
+		log.Fatalf("Failed to load pipeline: %v", err)
+	}
+
+	// Schemas that incoming events are checked against
+	schemas, err := ingest.LoadSchemaRegistry(cfg.SchemaPath, ingest.SchemaMode(cfg.SchemaMode))
+	if err != nil {
+		log.Fatalf("Failed to load schemas: %v", err)
+	}
+
+	// Open the write-ahead log that every accepted event is persisted to
+	wal, err := ingest.OpenWAL(ingest.WALConfig{
+		Dir:           cfg.WALDir,
+		SegmentBytes:  int64(cfg.WALSegmentBytes),
+		Fsync:         ingest.FsyncPolicy(cfg.WALFsync),
+		FsyncInterval: time.Duration(cfg.WALFsyncIntervalMs) * time.Millisecond,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open WAL in %s: %v", cfg.WALDir, err)
+	}
+
+	// Start worker that processes events and updates the store,
+	// replaying WAL events newer than the snapshot first
+	worker := ingest.NewWorker(queue, metricStore)
+	if err := worker.Replay(wal, snapshotSeq); err != nil {
+		log.Fatalf("Failed to replay WAL: %v", err)
+	}
+	worker.EnableSnapshots(cfg.SnapshotPath, time.Duration(cfg.SnapshotInterval)*time.Second, wal)
+	tail := ingest.NewTail()
+	worker.EnableTail(tail)
 	worker.Start()
 
-	// Initialize API key validator with keys from config
-	validator := auth.NewAPIKeyValidator(cfg.APIKeys)
+	// Initialize API key validator with keys from config; admin keys are
+	// valid everywhere
+	validator := auth.NewAPIKeyValidator(append(slices.Clone(cfg.APIKeys), cfg.AdminAPIKeys...))
 
 	// Create gRPC server with interceptors
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(metrics.UnaryServerInterceptor(store, validator)),
-		grpc.StreamInterceptor(metrics.StreamServerInterceptor(store, validator)),
+		grpc.UnaryInterceptor(metrics.UnaryServerInterceptor(metricStore, validator)),
+		grpc.StreamInterceptor(metrics.StreamServerInterceptor(metricStore, validator)),
 	)
 
+	// Build the catalog of metrics that clients can request by name
+	registry := metrics.NewRegistry()
+	if err := metrics.RegisterStoreMetrics(registry, metricStore); err != nil {
+		log.Fatalf("Failed to register metrics: %v", err)
+	}
+	if err := registerIngestMetrics(registry, queue, dedup, pipeline, clock, schemas, worker, tail); err != nil {
+		log.Fatalf("Failed to register metrics: %v", err)
+	}
+
 	// Register services
-	pb.RegisterIngestServiceServer(
-		grpcServer,
-		ingest.NewIngestService(eventChan),
-	)
-	pb.RegisterMetricsServiceServer(
-		grpcServer,
-		metrics.NewMetricsService(store),
+	ingestService := ingest.NewIngestService(queue, wal, clock)
+	ingestService.EnablePipeline(pipeline)
+	ingestService.EnableSchemas(schemas)
+	pb.RegisterIngestServiceServer(grpcServer, ingestService)
+	pb.RegisterSchemaServiceServer(grpcServer, ingest.NewSchemaService(schemas, cfg.AdminAPIKeys))
+	metricsService := metrics.NewMetricsService(metricStore, registry)
+	metricsService.SetSubscribeIntervals(
+		time.Duration(cfg.SubscribeMinIntervalMs)*time.Millisecond,
+		time.Duration(cfg.SubscribeMaxIntervalMs)*time.Millisecond,
 	)
+	metricsService.EnableTail(tail, cfg.AdminAPIKeys)
+	pb.RegisterMetricsServiceServer(grpcServer, metricsService)
 
 	// Start TCP listener on configured port
 	addr := fmt.Sprintf(":%d", cfg.GRPCPort)
@@ -56,12 +179,229 @@ func main() {
 	}
 
 	log.Printf("InsightIO analytics engine running on port %d", cfg.GRPCPort)
-	log.Printf("Metrics window: %d seconds", cfg.MetricsWindow)
+	log.Printf("Prometheus metrics on :%d/metrics", cfg.HTTPPort)
+	log.Printf("Metrics window: %d seconds (%dms buckets, %d seconds retention)", cfg.MetricsWindow, cfg.MetricsResolution, cfg.MetricsRetention)
+	log.Printf("Tracking up to %d event types, later ones are counted as %s", cfg.MaxEventTypes, store.OtherEventType)
+	log.Printf("WAL: %s (fsync=%s)", cfg.WALDir, cfg.WALFsync)
+	log.Printf("Ingest queue: %d events (overload policy %s), %d worker(s)", cfg.QueueSize, queue.Policy(), cfg.Workers)
+	if cfg.PartitionBy != "" {
+		log.Printf("Events partitioned across workers by %s", cfg.PartitionBy)
+	}
+	if dedup != nil {
+		log.Printf("Deduplicating event ids for %d seconds (%d exact, %d in Bloom filter)", cfg.DedupTTL, cfg.DedupMaxIDs, cfg.DedupBloomCapacity)
+	}
+	log.Printf("Event times accepted from %ds late to %ds ahead (%s otherwise)", cfg.AllowedLateness, cfg.FutureSkew, cfg.LateEventPolicy)
+	log.Printf("Pipeline: %d processor(s) from %s", pipeline.Len(), cfg.PipelinePath)
+	log.Printf("Schemas: %d loaded from %s (%s mode)", len(schemas.Schemas()), cfg.SchemaPath, schemas.Mode())
+	if len(cfg.AdminAPIKeys) == 0 {
+		log.Printf("No admin API key configured, schema admin RPCs and TailEvents are disabled")
+	}
+	if len(cfg.Dimensions) > 0 {
+		log.Printf("Dimensions: %s (max %d values each)", strings.Join(cfg.Dimensions, ", "), cfg.DimensionCardinality)
+	}
+	if metricStore.TracksSessions() {
+		log.Printf("Sessions end after %d seconds of inactivity", cfg.SessionGap)
+	}
+	if len(funnels) > 0 {
+		log.Printf("Funnels: %s", strings.Join(metricStore.FunnelNames(), ", "))
+	}
+	if sampled := pipeline.SampledTypes(); len(sampled) > 0 {
+		log.Printf("Sampled event types, left out of unique users, funnels and sessions: %s", strings.Join(sampled, ", "))
+	}
 	log.Printf("Environment: %s", cfg.Env)
 	log.Printf("API key validation enabled (%d key(s) configured)", len(cfg.APIKeys))
 
+	// Serve Prometheus metrics over HTTP
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.PrometheusHandler(metricStore, registry))
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.HTTPPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to serve HTTP metrics: %v", err)
+		}
+	}()
+
+	// On SIGINT/SIGTERM let in-flight RPCs finish, then drain the queue
+	// through the workers and flush everything to disk
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+
+		sigChan := make(chan os.Signal, 2)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+
+		log.Printf("Shutting down, waiting up to %s for in-flight requests", shutdownTimeout)
+		metricsService.Shutdown()
+		gracefulStop(grpcServer, shutdownTimeout, sigChan)
+	}()
+
 	// Start the server loop
 	if err := grpcServer.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve gRPC server: %v", err)
 	}
+	<-shutdownDone
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down HTTP server: %v", err)
+	}
+
+	// applies every queued event and writes the final snapshot
+	worker.Stop()
+	if err := wal.Close(); err != nil {
+		log.Printf("Failed to close WAL: %v", err)
+	}
+	log.Println("Shutdown complete")
+}
+
+// gracefulStop stops the gRPC server from accepting requests and waits for
+// in-flight ones until timeout or another signal, then cancels the rest.
+func gracefulStop(server *grpc.Server, timeout time.Duration, sigChan <-chan os.Signal) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return
+	case <-time.After(timeout):
+		log.Printf("In-flight requests still running after %s, cancelling them", timeout)
+	case <-sigChan:
+		log.Println("Received second signal, cancelling in-flight requests")
+	}
+
+	server.Stop()
+	<-stopped
+}
+
+// registerIngestMetrics adds the ingest pipeline's own counters to the registry.
+func registerIngestMetrics(registry *metrics.Registry, queue *ingest.Queue, dedup *ingest.Deduper, pipeline *ingest.Pipeline, clock *ingest.EventClock, schemas *ingest.SchemaRegistry, worker *ingest.Worker, tail *ingest.Tail) error {
+	duplicates := func() int64 { return 0 }
+	if dedup != nil {
+		duplicates = dedup.Duplicates
+	}
+
+	counters := []struct {
+		def   metrics.Definition
+		value func() int64
+	}{
+		{
+			metrics.Definition{Name: "ingest_duplicate_events", Description: "Events acked without being counted because their id was seen recently.", Unit: "events", Prometheus: "counter"},
+			duplicates,
+		},
+		{
+			metrics.Definition{Name: "ingest_dropped_events", Description: "Queued events discarded by the drop_oldest overload policy.", Unit: "events", Prometheus: "counter"},
+			queue.Dropped,
+		},
+		{
+			metrics.Definition{Name: "ingest_rejected_events", Description: "Events refused because the ingest queue was full.", Unit: "events", Prometheus: "counter"},
+			queue.Rejected,
+		},
+		{
+			metrics.Definition{Name: "ingest_filtered_events", Description: "Events dropped by a pipeline filter or sampled out.", Unit: "events", Prometheus: "counter"},
+			pipeline.Filtered,
+		},
+		{
+			metrics.Definition{Name: "tail_dropped_events", Description: "Live events dropped because a TailEvents client fell behind.", Unit: "events", Prometheus: "counter"},
+			tail.Dropped,
+		},
+	}
+
+	for _, c := range counters {
+		value := c.value
+		if err := registry.Register(c.def, metrics.Value(func(metrics.Query) float64 { return float64(value()) })); err != nil {
+			return err
+		}
+	}
+
+	err := registry.Register(
+		metrics.Definition{Name: "ingest_queue_depth", Description: "Events waiting for a worker, by partition.", Unit: "events", Labels: []string{"partition"}, Prometheus: "gauge"},
+		func(metrics.Query) []metrics.Sample {
+			lens := queue.PartitionLens()
+			samples := make([]metrics.Sample, len(lens))
+			for i, n := range lens {
+				samples[i] = metrics.Sample{Labels: map[string]string{"partition": strconv.Itoa(i)}, Value: float64(n)}
+			}
+			return samples
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	outOfRange := []struct {
+		def    metrics.Definition
+		counts func() (clamped, rejected int64)
+	}{
+		{
+			metrics.Definition{Name: "ingest_late_events", Description: "Events older than the allowed lateness, by action taken.", Unit: "events", Labels: []string{"action"}, Prometheus: "counter"},
+			clock.LateEvents,
+		},
+		{
+			metrics.Definition{Name: "ingest_future_events", Description: "Events further ahead than the allowed future skew, by action taken.", Unit: "events", Labels: []string{"action"}, Prometheus: "counter"},
+			clock.FutureEvents,
+		},
+	}
+
+	for _, c := range outOfRange {
+		counts := c.counts
+		err := registry.Register(c.def, func(metrics.Query) []metrics.Sample {
+			clamped, rejected := counts()
+			return []metrics.Sample{
+				{Labels: map[string]string{"action": "clamped"}, Value: float64(clamped)},
+				{Labels: map[string]string{"action": "rejected"}, Value: float64(rejected)},
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = registry.Register(
+		metrics.Definition{Name: "ingest_schema_violations", Description: "Events violating their schema since the server started, by event type and action taken.", Unit: "events", Labels: []string{"type", "action"}, Prometheus: "counter"},
+		func(metrics.Query) []metrics.Sample {
+			rejected, warned := schemas.Violations()
+			var samples []metrics.Sample
+			for _, c := range []struct {
+				action string
+				counts map[string]int64
+			}{{"rejected", rejected}, {"warned", warned}} {
+				types := make([]string, 0, len(c.counts))
+				for eventType := range c.counts {
+					types = append(types, eventType)
+				}
+				sort.Strings(types)
+				for _, eventType := range types {
+					samples = append(samples, metrics.Sample{Labels: map[string]string{"type": eventType, "action": c.action}, Value: float64(c.counts[eventType])})
+				}
+			}
+			return samples
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return registry.Register(
+		metrics.Definition{Name: "ingest_processing_latency", Description: "Time from enqueue until an event has been applied, since the server started.", Unit: "ms", Labels: []string{"stat"}, Prometheus: "gauge"},
+		func(metrics.Query) []metrics.Sample {
+			latency := worker.ProcessingLatency()
+			return []metrics.Sample{
+				{Labels: map[string]string{"stat": "avg"}, Value: latency.Avg},
+				{Labels: map[string]string{"stat": "p50"}, Value: latency.Median},
+				{Labels: map[string]string{"stat": "p95"}, Value: latency.P95},
+				{Labels: map[string]string{"stat": "p99"}, Value: latency.P99},
+				{Labels: map[string]string{"stat": "max"}, Value: latency.Max},
+			}
+		},
+	)
 }
@@ -50,19 +50,27 @@ func (v *APIKeyValidator) RemoveKey(key string) {
 	delete(v.validKeys, key)
 }
 
-// ValidateAPIKey validates the API key from the gRPC context metadata
-func (v *APIKeyValidator) ValidateAPIKey(ctx context.Context) error {
+// APIKeyFromContext returns the API key in the gRPC context metadata, or ""
+// if there is none
+func APIKeyFromContext(ctx context.Context) string {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return status.Error(codes.Unauthenticated, ErrMissingAPIKey.Error())
+		return ""
 	}
 
 	apiKeys := md.Get(apiKeyHeader)
-	if len(apiKeys) == 0 || apiKeys[0] == "" {
-		return status.Error(codes.Unauthenticated, ErrMissingAPIKey.Error())
+	if len(apiKeys) == 0 {
+		return ""
 	}
+	return apiKeys[0]
+}
 
-	apiKey := apiKeys[0]
+// ValidateAPIKey validates the API key from the gRPC context metadata
+func (v *APIKeyValidator) ValidateAPIKey(ctx context.Context) error {
+	apiKey := APIKeyFromContext(ctx)
+	if apiKey == "" {
+		return status.Error(codes.Unauthenticated, ErrMissingAPIKey.Error())
+	}
 
 	v.mu.RLock()
 	defer v.mu.RUnlock()
@@ -8,19 +8,122 @@ import (
 )
 
 type Config struct {
-	GRPCPort      int
-	MetricsWindow int
-	APIKey        string
-	APIKeys       []string // Parsed API keys (supports comma-separated)
-	Env           string
+	GRPCPort          int
+	HTTPPort          int // serves /metrics for Prometheus
+	MetricsWindow     int
+	MetricsResolution int // window bucket width in milliseconds
+	SketchResolution  int // unique-user sketch bucket width in seconds
+	MetricsRetention  int // longest window a query may ask for, in seconds
+	MaxEventTypes     int // event types tracked, later ones are counted together
+	APIKey            string
+	APIKeys           []string // Parsed API keys (supports comma-separated)
+	AdminAPIKeys      []string // keys allowed to call admin RPCs and TailEvents, none disables them; also valid as regular keys
+	Env               string
+
+	// Write-ahead log
+	WALDir             string
+	WALSegmentBytes    int
+	WALFsync           string // always, interval or never
+	WALFsyncIntervalMs int
+
+	// Queue between the ingest service and the workers
+	QueueSize      int
+	OverloadPolicy string // block, reject or drop_oldest
+	Workers        int
+	PartitionBy    string // "user_id" keeps each user's events in order, empty spreads events evenly unless funnels or sessions need order
+
+	// Duplicate detection by event id
+	DedupTTL           int // seconds
+	DedupMaxIDs        int // 0 disables deduplication
+	DedupBloomCapacity int // 0 disables the Bloom filter tier
+
+	// Accepted range of client event timestamps, in seconds around receive time
+	AllowedLateness int
+	FutureSkew      int
+	LateEventPolicy string // clamp or reject
+
+	// Processors applied to events before validation, declared in a JSON file
+	PipelinePath string
+
+	// Event schemas enforced at ingest
+	SchemaPath string
+	SchemaMode string // strict or warn
+
+	// Metric store snapshots
+	SnapshotPath     string
+	SnapshotInterval int // seconds
+
+	// Metadata keys to break events down by
+	Dimensions           []string
+	DimensionCardinality int // distinct values tracked per dimension
+
+	// Conversion funnels, declared in a JSON file
+	FunnelsPath string
+	FunnelUsers int // users with a funnel in progress tracked per funnel
+
+	// User sessions
+	SessionGap   int // seconds of inactivity that end a session, 0 disables sessions
+	SessionUsers int // users with an open session tracked
+
+	// Bounds on the push interval a SubscribeMetrics client may ask for
+	SubscribeMinIntervalMs int
+	SubscribeMaxIntervalMs int
+
+	// How long in-flight RPCs may take to finish on shutdown, in seconds
+	ShutdownTimeout int
 }
 
 func Load() *Config {
 	cfg := &Config{
-		GRPCPort:      getEnvAsInt("INSIGHTIO_GRPC_PORT", 50051),
-		MetricsWindow: getEnvAsInt("INSIGHTIO_METRICS_WINDOW", 60),
-		APIKey:        getEnv("INSIGHTIO_API_KEY", ""),
-		Env:           getEnv("INSIGHTIO_ENV", "dev"),
+		GRPCPort:          getEnvAsInt("INSIGHTIO_GRPC_PORT", 50051),
+		HTTPPort:          getEnvAsInt("INSIGHTIO_HTTP_PORT", 9090),
+		MetricsWindow:     getEnvAsInt("INSIGHTIO_METRICS_WINDOW", 60),
+		MetricsResolution: getEnvAsInt("INSIGHTIO_METRICS_RESOLUTION_MS", 1000),
+		SketchResolution:  getEnvAsInt("INSIGHTIO_SKETCH_RESOLUTION", 10),
+		MetricsRetention:  getEnvAsInt("INSIGHTIO_METRICS_RETENTION", 3600),
+		MaxEventTypes:     getEnvAsInt("INSIGHTIO_MAX_EVENT_TYPES", 200),
+		APIKey:            getEnv("INSIGHTIO_API_KEY", ""),
+		Env:               getEnv("INSIGHTIO_ENV", "dev"),
+
+		WALDir:             getEnv("INSIGHTIO_WAL_DIR", "data/wal"),
+		WALSegmentBytes:    getEnvAsInt("INSIGHTIO_WAL_SEGMENT_BYTES", 64<<20),
+		WALFsync:           getEnv("INSIGHTIO_WAL_FSYNC", "interval"),
+		WALFsyncIntervalMs: getEnvAsInt("INSIGHTIO_WAL_FSYNC_INTERVAL_MS", 1000),
+
+		QueueSize:      getEnvAsInt("INSIGHTIO_QUEUE_SIZE", 1000),
+		OverloadPolicy: getEnv("INSIGHTIO_OVERLOAD_POLICY", "block"),
+		Workers:        getEnvAsInt("INSIGHTIO_WORKERS", 1),
+		PartitionBy:    getEnv("INSIGHTIO_PARTITION_BY", ""),
+
+		DedupTTL:           getEnvAsInt("INSIGHTIO_DEDUP_TTL", 600),
+		DedupMaxIDs:        getEnvAsInt("INSIGHTIO_DEDUP_MAX_IDS", 100000),
+		DedupBloomCapacity: getEnvAsInt("INSIGHTIO_DEDUP_BLOOM_CAPACITY", 0),
+
+		AllowedLateness: getEnvAsInt("INSIGHTIO_ALLOWED_LATENESS", 3600),
+		FutureSkew:      getEnvAsInt("INSIGHTIO_FUTURE_SKEW", 60),
+		LateEventPolicy: getEnv("INSIGHTIO_LATE_EVENT_POLICY", "clamp"),
+
+		PipelinePath: getEnv("INSIGHTIO_PIPELINE_PATH", "data/pipeline.json"),
+
+		SchemaPath: getEnv("INSIGHTIO_SCHEMA_PATH", "data/schemas.json"),
+		SchemaMode: getEnv("INSIGHTIO_SCHEMA_MODE", "strict"),
+
+		SnapshotPath:     getEnv("INSIGHTIO_SNAPSHOT_PATH", "data/snapshot.json"),
+		SnapshotInterval: getEnvAsInt("INSIGHTIO_SNAPSHOT_INTERVAL", 30),
+
+		Dimensions:           getEnvAsList("INSIGHTIO_DIMENSIONS"),
+		DimensionCardinality: getEnvAsInt("INSIGHTIO_DIMENSION_CARDINALITY", 100),
+
+		FunnelsPath: getEnv("INSIGHTIO_FUNNELS_PATH", "data/funnels.json"),
+		FunnelUsers: getEnvAsInt("INSIGHTIO_FUNNEL_USERS", 100000),
+
+		SessionGap:   getEnvAsInt("INSIGHTIO_SESSION_GAP", 1800),
+		SessionUsers: getEnvAsInt("INSIGHTIO_SESSION_USERS", 100000),
+
+		SubscribeMinIntervalMs: getEnvAsInt("INSIGHTIO_SUBSCRIBE_MIN_INTERVAL_MS", 500),
+		SubscribeMaxIntervalMs: getEnvAsInt("INSIGHTIO_SUBSCRIBE_MAX_INTERVAL_MS", 60000),
+
+		ShutdownTimeout: getEnvAsInt("INSIGHTIO_SHUTDOWN_TIMEOUT", 20),
 	}
 
 	// Parse API keys - support comma-separated values
@@ -29,19 +132,14 @@ func Load() *Config {
 	}
 
 	// Split by comma and trim whitespace
-	keys := strings.Split(cfg.APIKey, ",")
-	cfg.APIKeys = make([]string, 0, len(keys))
-	for _, key := range keys {
-		trimmed := strings.TrimSpace(key)
-		if trimmed != "" {
-			cfg.APIKeys = append(cfg.APIKeys, trimmed)
-		}
-	}
+	cfg.APIKeys = splitList(cfg.APIKey)
 
 	if len(cfg.APIKeys) == 0 {
 		log.Fatal("INSIGHTIO_API_KEY must contain at least one valid API key")
 	}
 
+	cfg.AdminAPIKeys = getEnvAsList("INSIGHTIO_ADMIN_API_KEY")
+
 	return cfg
 }
 func getEnv(key string, defaultVal string) string {
@@ -64,3 +162,20 @@ func getEnvAsInt(key string, defaultVal int) int {
 
 	return val
 }
+
+// getEnvAsList reads a comma-separated list, dropping empty entries
+func getEnvAsList(key string) []string {
+	return splitList(getEnv(key, ""))
+}
+
+func splitList(val string) []string {
+	parts := strings.Split(val, ",")
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
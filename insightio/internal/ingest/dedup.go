@@ -0,0 +1,256 @@
+package ingest
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDedupTTL is how long event ids are remembered when no TTL is configured.
+const DefaultDedupTTL = 10 * time.Minute
+
+// bloomFalsePositiveRate is the rate the Bloom tier is sized for. A false
+// positive acks a new event as a duplicate, so it is kept low.
+const bloomFalsePositiveRate = 0.001
+
+// DedupConfig configures duplicate detection.
+type DedupConfig struct {
+	TTL           time.Duration // how long an id is remembered
+	MaxIDs        int           // ids remembered exactly, oldest evicted first
+	BloomCapacity int           // evicted ids remembered approximately, 0 disables the Bloom tier
+}
+
+// Deduper remembers recently seen event ids so retried events are acked
+// without being counted twice. Ids are kept exactly up to MaxIDs; ids
+// evicted before their TTL fall through to an optional Bloom filter, which
+// may mistake a small fraction of new ids for duplicates. Ids survive a
+// restart as far as the WAL still holds their events; see Worker.Replay.
+type Deduper struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	ids   map[string]time.Time // id -> when it was remembered
+	fifo  []dedupEntry         // ring of ids in the order first seen
+	head  int                  // oldest entry
+	count int
+	bloom *rotatingBloom
+
+	duplicates atomic.Int64
+}
+
+type dedupEntry struct {
+	id   string
+	seen time.Time
+}
+
+// NewDeduper returns a deduper, or nil if cfg.MaxIDs is not positive.
+func NewDeduper(cfg DedupConfig) *Deduper {
+	if cfg.MaxIDs <= 0 {
+		return nil
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultDedupTTL
+	}
+
+	d := &Deduper{
+		ttl:  cfg.TTL,
+		ids:  make(map[string]time.Time, cfg.MaxIDs),
+		fifo: make([]dedupEntry, cfg.MaxIDs),
+	}
+	if cfg.BloomCapacity > 0 {
+		d.bloom = newRotatingBloom(cfg.BloomCapacity, cfg.TTL)
+	}
+	return d
+}
+
+// Duplicates returns the number of events recognized as duplicates.
+func (d *Deduper) Duplicates() int64 {
+	return d.duplicates.Load()
+}
+
+// seen reports whether id was remembered within the TTL.
+func (d *Deduper) seen(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(now)
+	return d.known(id, now)
+}
+
+// claim reports whether id was remembered within the TTL, and remembers it
+// if not, in one step.
+func (d *Deduper) claim(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(now)
+	if d.known(id, now) {
+		return true
+	}
+	d.add(id, now)
+	return false
+}
+
+// forget undoes the claim of an event that was not accepted after all.
+func (d *Deduper) forget(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.ids, id)
+}
+
+// remember records id as seen at now.
+func (d *Deduper) remember(id string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(now)
+	if _, ok := d.ids[id]; ok {
+		return
+	}
+	d.add(id, now)
+}
+
+// known counts and reports a remembered id. Callers hold d.mu.
+func (d *Deduper) known(id string, now time.Time) bool {
+	_, ok := d.ids[id]
+	if !ok && d.bloom != nil {
+		ok = d.bloom.contains(id, now)
+	}
+	if ok {
+		d.duplicates.Add(1)
+	}
+	return ok
+}
+
+// add remembers an id that is not known. Callers hold d.mu.
+func (d *Deduper) add(id string, now time.Time) {
+	if d.count == len(d.fifo) {
+		// full: the oldest id moves to the Bloom tier, if any
+		if oldest, live := d.pop(); live && d.bloom != nil {
+			d.bloom.add(oldest.id, now)
+		}
+	}
+	d.fifo[(d.head+d.count)%len(d.fifo)] = dedupEntry{id: id, seen: now}
+	d.count++
+	d.ids[id] = now
+}
+
+// expire forgets ids older than the TTL. Entries are in time order, so
+// expiry stops at the first live one. Callers hold d.mu.
+func (d *Deduper) expire(now time.Time) {
+	for d.count > 0 && now.Sub(d.fifo[d.head].seen) >= d.ttl {
+		d.pop()
+	}
+}
+
+// pop removes and returns the oldest entry, reporting whether its id was
+// still remembered rather than forgotten or remembered again since.
+// Callers hold d.mu.
+func (d *Deduper) pop() (dedupEntry, bool) {
+	oldest := d.fifo[d.head]
+	d.fifo[d.head] = dedupEntry{}
+	d.head = (d.head + 1) % len(d.fifo)
+	d.count--
+
+	seen, ok := d.ids[oldest.id]
+	live := ok && seen.Equal(oldest.seen)
+	if live {
+		delete(d.ids, oldest.id)
+	}
+	return oldest, live
+}
+
+// rotatingBloom remembers ids for between one and two TTLs using two
+// generations of Bloom filters. A generation also rotates once it holds
+// capacity ids, so the false positive rate stays bounded under load at the
+// cost of forgetting ids sooner.
+type rotatingBloom struct {
+	ttl      time.Duration
+	capacity int
+	seed     maphash.Seed
+	current  *bloomFilter
+	previous *bloomFilter
+	added    int // ids added to current
+	rotated  time.Time
+}
+
+func newRotatingBloom(capacity int, ttl time.Duration) *rotatingBloom {
+	return &rotatingBloom{
+		ttl:      ttl,
+		capacity: capacity,
+		seed:     maphash.MakeSeed(),
+		current:  newBloomFilter(capacity, bloomFalsePositiveRate),
+		previous: newBloomFilter(capacity, bloomFalsePositiveRate),
+		rotated:  time.Now(),
+	}
+}
+
+func (r *rotatingBloom) rotate(now time.Time) {
+	elapsed := now.Sub(r.rotated)
+	if elapsed < r.ttl && r.added < r.capacity {
+		return
+	}
+	r.previous, r.current = r.current, r.previous
+	r.current.reset()
+	if elapsed >= 2*r.ttl {
+		// nothing in the previous generation is within the TTL either
+		r.previous.reset()
+	}
+	r.added = 0
+	r.rotated = now
+}
+
+func (r *rotatingBloom) add(id string, now time.Time) {
+	r.rotate(now)
+	r.current.add(maphash.String(r.seed, id))
+	r.added++
+}
+
+func (r *rotatingBloom) contains(id string, now time.Time) bool {
+	r.rotate(now)
+	h := maphash.String(r.seed, id)
+	return r.current.contains(h) || r.previous.contains(h)
+}
+
+// bloomFilter is a fixed-size Bloom filter using double hashing.
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64 // number of bits
+	hashes int
+}
+
+// newBloomFilter sizes a filter for n entries at false positive rate p.
+func newBloomFilter(n int, p float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &bloomFilter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		hashes: max(k, 1),
+	}
+}
+
+func (b *bloomFilter) add(h uint64) {
+	h1, h2 := h, h>>32|1
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloomFilter) contains(h uint64) bool {
+	h1, h2 := h, h>>32|1
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomFilter) reset() {
+	clear(b.bits)
+}
@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
+)
+
+func TestDeduperClaim(t *testing.T) {
+	now := time.Now()
+	d := NewDeduper(DedupConfig{TTL: time.Minute, MaxIDs: 2})
+
+	steps := []struct {
+		name string
+		id   string
+		at   time.Duration
+		want bool // reported as a duplicate
+	}{
+		{"new", "a", 0, false},
+		{"retry", "a", time.Second, true},
+		{"second id", "b", time.Second, false},
+		{"evicts oldest", "c", time.Second, false},
+		{"evicted id is new again", "a", 2 * time.Second, false},
+		{"remembered id", "c", 2 * time.Second, true},
+		{"expired id is new again", "c", 2 * time.Minute, false},
+	}
+	for _, s := range steps {
+		if got := d.claim(s.id, now.Add(s.at)); got != s.want {
+			t.Errorf("%s: claim(%q) = %v, want %v", s.name, s.id, got, s.want)
+		}
+	}
+	if got := d.Duplicates(); got != 2 {
+		t.Errorf("Duplicates = %d, want 2", got)
+	}
+}
+
+func TestDeduperForget(t *testing.T) {
+	now := time.Now()
+	d := NewDeduper(DedupConfig{TTL: time.Minute, MaxIDs: 10})
+
+	d.claim("a", now)
+	d.forget("a")
+	if d.claim("a", now) {
+		t.Error("forgotten id claimed as a duplicate")
+	}
+	if !d.seen("a", now) {
+		t.Error("reclaimed id not seen")
+	}
+}
+
+func TestDeduperBloomTier(t *testing.T) {
+	d := NewDeduper(DedupConfig{TTL: time.Minute, MaxIDs: 2, BloomCapacity: 100})
+	now := time.Now()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		d.claim(id, now)
+	}
+	// a and b were evicted from the exact tier into the Bloom filter
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if !d.seen(id, now) {
+			t.Errorf("%q not seen", id)
+		}
+	}
+	// the Bloom tier forgets ids after at most two TTLs
+	if d.seen("a", now.Add(2*time.Minute)) {
+		t.Error("id seen after two TTLs")
+	}
+}
+
+func TestRotatingBloomOverfilled(t *testing.T) {
+	const capacity = 1000
+	now := time.Now()
+	r := newRotatingBloom(capacity, time.Hour)
+
+	// far more ids than the capacity within a single TTL
+	for i := 0; i < 20*capacity; i++ {
+		r.add(fmt.Sprintf("added-%d", i), now)
+	}
+	// the latest capacity ids are remembered
+	for i := 19 * capacity; i < 20*capacity; i++ {
+		if !r.contains(fmt.Sprintf("added-%d", i), now) {
+			t.Fatalf("recently added id %d not found", i)
+		}
+	}
+
+	// each generation holds at most capacity ids, so a new id matches one
+	// of the two at no more than about twice the target rate
+	const probes = 100000
+	falsePositives := 0
+	for i := 0; i < probes; i++ {
+		if r.contains(fmt.Sprintf("new-%d", i), now) {
+			falsePositives++
+		}
+	}
+	if rate, limit := float64(falsePositives)/probes, 3*bloomFalsePositiveRate; rate > limit {
+		t.Errorf("false positive rate %.4f after overfilling, want at most %.4f", rate, limit)
+	}
+}
+
+func TestDropOldestForgetsID(t *testing.T) {
+	d := NewDeduper(DedupConfig{TTL: time.Minute, MaxIDs: 10})
+	q, err := NewQueue(QueueConfig{Size: 1, Policy: OverloadDropOldest, Dedup: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := openTestWAL(t, t.TempDir(), 100)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		if err := q.push(ctx, w, testEvent(i), 1); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	// the first event was dropped to make room, so its retry is new
+	if err := q.push(ctx, w, testEvent(1), 1); err != nil {
+		t.Errorf("retry of a dropped event: %v", err)
+	}
+	if err := q.push(ctx, w, testEvent(1), 1); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second retry error = %v, want %v", err, ErrDuplicate)
+	}
+}
+
+func TestReplayRemembersIDs(t *testing.T) {
+	dir := t.TempDir()
+	w := openTestWAL(t, dir, 100)
+	appendEvents(t, w, 1, 6)
+	w.Close()
+
+	d := NewDeduper(DedupConfig{TTL: time.Minute, MaxIDs: 10})
+	q, err := NewQueue(QueueConfig{Dedup: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := store.NewMetricStore(60)
+	w = openTestWAL(t, dir, 100)
+
+	// a snapshot covered the first four events
+	if err := NewWorker(q, m).Replay(w, 4); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	// each event is weighted by its index
+	if got := m.GetTotalEvents(); got != 5+6 {
+		t.Errorf("replayed %d events, want %d", got, 5+6)
+	}
+	for i := 1; i <= 6; i++ {
+		if !d.seen(testEvent(i).Id, time.Now()) {
+			t.Errorf("id of event %d not remembered", i)
+		}
+	}
+}
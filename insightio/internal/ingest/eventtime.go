@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LatePolicy decides what happens to an event whose timestamp is outside
+// the accepted range.
+type LatePolicy string
+
+const (
+	LateClamp  LatePolicy = "clamp"  // move the timestamp to the nearest accepted time
+	LateReject LatePolicy = "reject" // refuse the event
+)
+
+var (
+	// ErrTooLate is returned for an event older than the allowed lateness.
+	ErrTooLate = errors.New("event timestamp is older than the allowed lateness")
+
+	// ErrTooEarly is returned for an event further ahead than the future skew.
+	ErrTooEarly = errors.New("event timestamp is too far in the future")
+)
+
+// EventTimeConfig bounds the timestamps clients may send.
+type EventTimeConfig struct {
+	AllowedLateness time.Duration // how far behind the receive time an event may be
+	FutureSkew      time.Duration // how far ahead of the receive time an event may be
+	Policy          LatePolicy
+}
+
+// EventClock assigns every incoming event the time it is bucketed by. Events
+// keep their client timestamp when it is within bounds; events without one
+// get the receive time. The result is written to the event before it is
+// persisted, so replay buckets it the same way.
+type EventClock struct {
+	cfg EventTimeConfig
+
+	lateClamped   atomic.Int64
+	lateRejected  atomic.Int64
+	earlyClamped  atomic.Int64
+	earlyRejected atomic.Int64
+}
+
+// NewEventClock creates an event clock from cfg.
+func NewEventClock(cfg EventTimeConfig) (*EventClock, error) {
+	if cfg.Policy == "" {
+		cfg.Policy = LateClamp
+	}
+	switch cfg.Policy {
+	case LateClamp, LateReject:
+	default:
+		return nil, fmt.Errorf("unknown late event policy %q", cfg.Policy)
+	}
+	if cfg.AllowedLateness < 0 || cfg.FutureSkew < 0 {
+		return nil, errors.New("allowed lateness and future skew must not be negative")
+	}
+	return &EventClock{cfg: cfg}, nil
+}
+
+// stamp sets event.Timestamp to the time the event is bucketed by, or
+// returns ErrTooLate or ErrTooEarly if the policy rejects it.
+func (c *EventClock) stamp(event *pb.Event, now time.Time) error {
+	if event.Timestamp == nil {
+		event.Timestamp = timestamppb.New(now)
+		return nil
+	}
+
+	t := event.Timestamp.AsTime()
+	earliest := now.Add(-c.cfg.AllowedLateness)
+	latest := now.Add(c.cfg.FutureSkew)
+
+	switch {
+	case t.Before(earliest):
+		if c.cfg.Policy == LateReject {
+			c.lateRejected.Add(1)
+			return ErrTooLate
+		}
+		c.lateClamped.Add(1)
+		event.Timestamp = timestamppb.New(earliest)
+
+	case t.After(latest):
+		if c.cfg.Policy == LateReject {
+			c.earlyRejected.Add(1)
+			return ErrTooEarly
+		}
+		c.earlyClamped.Add(1)
+		event.Timestamp = timestamppb.New(latest)
+	}
+	return nil
+}
+
+// LateEvents returns the number of events older than the allowed lateness
+// that were clamped and rejected.
+func (c *EventClock) LateEvents() (clamped, rejected int64) {
+	return c.lateClamped.Load(), c.lateRejected.Load()
+}
+
+// FutureEvents returns the number of events further ahead than the future
+// skew that were clamped and rejected.
+func (c *EventClock) FutureEvents() (clamped, rejected int64) {
+	return c.earlyClamped.Load(), c.earlyRejected.Load()
+}
+
+// eventTime returns the time an event is bucketed by. Events persisted
+// before timestamps were assigned fall back to now.
+func eventTime(event *pb.Event, now time.Time) time.Time {
+	if event.Timestamp == nil {
+		return now
+	}
+	return event.Timestamp.AsTime()
+}
@@ -0,0 +1,126 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+func TestEventClockStamp(t *testing.T) {
+	now := time.Now()
+	cfg := EventTimeConfig{AllowedLateness: time.Hour, FutureSkew: time.Minute}
+
+	tests := []struct {
+		name    string
+		policy  LatePolicy
+		sent    *time.Time // nil sends no timestamp
+		want    time.Time
+		wantErr error
+	}{
+		{"no timestamp", LateClamp, nil, now, nil},
+		{"within bounds", LateClamp, ptr(now.Add(-30 * time.Minute)), now.Add(-30 * time.Minute), nil},
+		{"at the lateness bound", LateReject, ptr(now.Add(-time.Hour)), now.Add(-time.Hour), nil},
+		{"late clamped", LateClamp, ptr(now.Add(-2 * time.Hour)), now.Add(-time.Hour), nil},
+		{"late rejected", LateReject, ptr(now.Add(-2 * time.Hour)), time.Time{}, ErrTooLate},
+		{"future clamped", LateClamp, ptr(now.Add(time.Hour)), now.Add(time.Minute), nil},
+		{"future rejected", LateReject, ptr(now.Add(time.Hour)), time.Time{}, ErrTooEarly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.Policy = tt.policy
+			clock, err := NewEventClock(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			event := &pb.Event{Type: "click"}
+			if tt.sent != nil {
+				event.Timestamp = timestamppb.New(*tt.sent)
+			}
+			err = clock.stamp(event, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("stamp error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := event.Timestamp.AsTime(); !got.Equal(tt.want) {
+				t.Errorf("stamped %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventClockCounts(t *testing.T) {
+	now := time.Now()
+	for _, policy := range []LatePolicy{LateClamp, LateReject} {
+		t.Run(string(policy), func(t *testing.T) {
+			clock, err := NewEventClock(EventTimeConfig{AllowedLateness: time.Hour, FutureSkew: time.Minute, Policy: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sent := []time.Duration{-2 * time.Hour, -3 * time.Hour, time.Hour, 0}
+			for _, d := range sent {
+				clock.stamp(&pb.Event{Timestamp: timestamppb.New(now.Add(d))}, now)
+			}
+
+			lateClamped, lateRejected := clock.LateEvents()
+			futureClamped, futureRejected := clock.FutureEvents()
+			want := [4]int64{2, 0, 1, 0}
+			if policy == LateReject {
+				want = [4]int64{0, 2, 0, 1}
+			}
+			if got := [4]int64{lateClamped, lateRejected, futureClamped, futureRejected}; got != want {
+				t.Errorf("late clamped/rejected, future clamped/rejected = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewEventClockInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EventTimeConfig
+	}{
+		{"unknown policy", EventTimeConfig{Policy: "drop"}},
+		{"negative lateness", EventTimeConfig{AllowedLateness: -time.Second}},
+		{"negative skew", EventTimeConfig{FutureSkew: -time.Second}},
+	}
+	for _, tt := range tests {
+		if _, err := NewEventClock(tt.cfg); err == nil {
+			t.Errorf("%s: NewEventClock accepted %+v", tt.name, tt.cfg)
+		}
+	}
+}
+
+func TestApplyBucketsByEventTime(t *testing.T) {
+	now := time.Now()
+	m := store.NewMetricStoreWithOptions(store.Options{Window: time.Minute, Resolution: time.Second, Retention: time.Hour})
+	w := NewWorker(nil, m)
+
+	w.apply(&pb.Event{Type: "click", Timestamp: timestamppb.New(now.Add(-10 * time.Second))}, 0)
+	w.apply(&pb.Event{Type: "click", Timestamp: timestamppb.New(now.Add(-30 * time.Minute))}, 0)
+
+	if got := m.GetTotalEvents(); got != 2 {
+		t.Errorf("total events = %d, want 2", got)
+	}
+	// the late event counts, but not within the last minute
+	if got := m.GetEventsInWindow(time.Minute); got != 1 {
+		t.Errorf("events in the last minute = %d, want 1", got)
+	}
+	if got := m.GetEventsInWindow(time.Hour); got != 2 {
+		t.Errorf("events in the last hour = %d, want 2", got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
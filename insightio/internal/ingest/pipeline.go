@@ -0,0 +1,406 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/auth"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// Processor transforms an event before it is validated and persisted.
+// Returning false drops the event.
+type Processor interface {
+	Process(ctx context.Context, event *pb.Event) bool
+}
+
+// ProcessorFunc adapts a function to a Processor.
+type ProcessorFunc func(ctx context.Context, event *pb.Event) bool
+
+func (f ProcessorFunc) Process(ctx context.Context, event *pb.Event) bool {
+	return f(ctx, event)
+}
+
+// Pipeline runs processors in order, stopping at the first that drops the
+// event. Events are processed on the RPC that received them, so processors
+// can read the caller's peer address and API key from ctx.
+type Pipeline struct {
+	processors []Processor
+	filtered   atomic.Int64
+}
+
+// NewPipeline creates a pipeline running processors in order.
+func NewPipeline(processors ...Processor) *Pipeline {
+	return &Pipeline{processors: processors}
+}
+
+// Len returns the number of processors.
+func (p *Pipeline) Len() int {
+	return len(p.processors)
+}
+
+// SampledTypes returns the event types a sample stage keeps only some of.
+func (p *Pipeline) SampledTypes() []string {
+	var types []string
+	for _, processor := range p.processors {
+		if stage, ok := processor.(*sampleStage); ok {
+			for eventType, rate := range stage.rates {
+				if rate < 1 && !slices.Contains(types, eventType) {
+					types = append(types, eventType)
+				}
+			}
+		}
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Filtered returns the number of events dropped by a processor.
+func (p *Pipeline) Filtered() int64 {
+	return p.filtered.Load()
+}
+
+// process runs every processor on the event and reports whether it is
+// kept, and at which rate if sample stages kept it; 1 if none sampled it.
+func (p *Pipeline) process(ctx context.Context, event *pb.Event) (float64, bool) {
+	rate := 1.0
+	for _, processor := range p.processors {
+		if stage, ok := processor.(*sampleStage); ok {
+			r, kept := stage.sample(event)
+			if !kept {
+				p.filtered.Add(1)
+				return 0, false
+			}
+			// rates of successive sample stages multiply
+			rate *= r
+			continue
+		}
+		if !processor.Process(ctx, event) {
+			p.filtered.Add(1)
+			return 0, false
+		}
+	}
+	return rate, true
+}
+
+// ProcessorConfig declares one pipeline stage. Kind selects the stage and
+// decides which of the other fields apply:
+//
+//	receive_time     writes the server receive time (RFC 3339) to metadata Key
+//	peer_address     writes the caller's IP address to metadata Key; with
+//	                 Forwarded, the address the gateway received the event
+//	                 from, i.e. the last x-forwarded-for entry, when present
+//	tenant           writes the tenant of the caller's API key to metadata Key,
+//	                 from Tenants or, for unlisted keys, a fingerprint of the key
+//	tags             writes Tags to metadata, replacing client values
+//	drop             drops events whose type is in Types or whose metadata
+//	                 contains one of the Metadata pairs
+//	keep             drops every event drop would keep
+//	rename_type      renames event types from the keys of Renames to its values
+//	rename_metadata  renames metadata keys from the keys of Renames to its values,
+//	                 all at once
+//	sample           keeps events of the types in Rates with the given
+//	                 probability, deciding per user (per event id for events
+//	                 without one); event counts, value aggregates and
+//	                 dimensions count every kept event as 1/rate events.
+//	                 Sampled events are left out of unique users, funnels and
+//	                 sessions, which cannot be scaled, so sample only types
+//	                 those do not need
+type ProcessorConfig struct {
+	Kind     string             `json:"kind"`
+	Key      string             `json:"key,omitempty"`
+	Tenants  map[string]string  `json:"tenants,omitempty"` // API key -> tenant
+	Tags     map[string]string  `json:"tags,omitempty"`
+	Types    []string           `json:"types,omitempty"`
+	Metadata map[string]string  `json:"metadata,omitempty"`
+	Renames  map[string]string  `json:"renames,omitempty"`
+	Rates    map[string]float64 `json:"rates,omitempty"` // event type -> fraction kept
+
+	// trust the x-forwarded-for metadata the gateway sends; only set when
+	// every caller is a gateway, since callers can send any metadata
+	Forwarded bool `json:"forwarded,omitempty"`
+}
+
+// pipelineFile is the on-disk form of a pipeline.
+type pipelineFile struct {
+	Processors []ProcessorConfig `json:"processors"`
+}
+
+// LoadPipeline builds the pipeline declared in path. A missing file yields
+// an empty pipeline.
+func LoadPipeline(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewPipeline(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file pipelineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	processors := make([]Processor, len(file.Processors))
+	for i, cfg := range file.Processors {
+		processors[i], err = newProcessor(i, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("processor %d in %s: %w", i, path, err)
+		}
+	}
+	return NewPipeline(processors...), nil
+}
+
+// newProcessor builds the stage cfg declares at position stage.
+func newProcessor(stage int, cfg ProcessorConfig) (Processor, error) {
+	switch cfg.Kind {
+	case "receive_time", "peer_address", "tenant":
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("%s needs a metadata key", cfg.Kind)
+		}
+	case "tags":
+		if len(cfg.Tags) == 0 {
+			return nil, errors.New("tags needs at least one tag")
+		}
+	case "drop", "keep":
+		if len(cfg.Types) == 0 && len(cfg.Metadata) == 0 {
+			return nil, fmt.Errorf("%s needs types or metadata to match", cfg.Kind)
+		}
+	case "rename_type", "rename_metadata":
+		if len(cfg.Renames) == 0 {
+			return nil, fmt.Errorf("%s needs at least one rename", cfg.Kind)
+		}
+		for from, to := range cfg.Renames {
+			if from == "" || to == "" {
+				return nil, fmt.Errorf("%s cannot rename %q to %q", cfg.Kind, from, to)
+			}
+		}
+	case "sample":
+		if len(cfg.Rates) == 0 {
+			return nil, errors.New("sample needs at least one rate")
+		}
+		for eventType, rate := range cfg.Rates {
+			if !(rate > 0 && rate <= 1) {
+				return nil, fmt.Errorf("sample rate of %q must be in (0, 1], use drop to discard every event", eventType)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown processor kind %q", cfg.Kind)
+	}
+
+	switch cfg.Kind {
+	case "receive_time":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			setMetadata(event, cfg.Key, time.Now().UTC().Format(time.RFC3339Nano))
+			return true
+		}), nil
+
+	case "peer_address":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			if addr := forwardedFor(ctx); cfg.Forwarded && addr != "" {
+				setMetadata(event, cfg.Key, addr)
+			} else if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+				setMetadata(event, cfg.Key, peerHost(p.Addr))
+			}
+			return true
+		}), nil
+
+	case "tenant":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			if key := auth.APIKeyFromContext(ctx); key != "" {
+				setMetadata(event, cfg.Key, tenantOf(cfg.Tenants, key))
+			}
+			return true
+		}), nil
+
+	case "tags":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			for k, v := range cfg.Tags {
+				setMetadata(event, k, v)
+			}
+			return true
+		}), nil
+
+	case "drop":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			return !matches(cfg, event)
+		}), nil
+
+	case "keep":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			return matches(cfg, event)
+		}), nil
+
+	case "rename_type":
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			if to, ok := cfg.Renames[event.Type]; ok {
+				event.Type = to
+			}
+			return true
+		}), nil
+
+	case "sample":
+		return &sampleStage{stage: stage, rates: cfg.Rates}, nil
+
+	default: // rename_metadata
+		return ProcessorFunc(func(ctx context.Context, event *pb.Event) bool {
+			// all keys are renamed at once, so with a -> b and b -> c
+			// the value of a ends up in b, not c
+			renamed := make(map[string]string)
+			for from, to := range cfg.Renames {
+				if v, ok := event.Metadata[from]; ok {
+					renamed[to] = v
+					delete(event.Metadata, from)
+				}
+			}
+			for k, v := range renamed {
+				event.Metadata[k] = v
+			}
+			return true
+		}), nil
+	}
+}
+
+// matches reports whether the event has one of the types or metadata pairs
+// of a drop or keep stage.
+func matches(cfg ProcessorConfig, event *pb.Event) bool {
+	if slices.Contains(cfg.Types, event.Type) {
+		return true
+	}
+	for k, v := range cfg.Metadata {
+		if got, ok := event.Metadata[k]; ok && got == v {
+			return true
+		}
+	}
+	return false
+}
+
+func setMetadata(event *pb.Event, key, value string) {
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]string)
+	}
+	event.Metadata[key] = value
+}
+
+// peerHost returns the IP of a TCP peer, or the whole address otherwise.
+func peerHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// forwardedFor returns the last address of the x-forwarded-for metadata,
+// the one the gateway saw, or "" if there is none.
+func forwardedFor(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("x-forwarded-for")
+	if len(values) == 0 {
+		return ""
+	}
+	addrs := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(addrs[len(addrs)-1])
+}
+
+// sampleStage keeps events of some types at a rate. The pipeline reads the
+// rate back to weight the events it keeps.
+type sampleStage struct {
+	stage int
+	rates map[string]float64 // event type -> fraction kept
+}
+
+func (s *sampleStage) Process(ctx context.Context, event *pb.Event) bool {
+	_, kept := s.sample(event)
+	return kept
+}
+
+// sample reports whether the event is kept and the rate its type is kept
+// at, 1 if it is not sampled.
+func (s *sampleStage) sample(event *pb.Event) (float64, bool) {
+	rate, ok := s.rates[event.Type]
+	if !ok || rate == 1 {
+		return 1, true
+	}
+	return rate, sampled(s.stage, event, rate)
+}
+
+// sampleWeight returns how many events an event kept at rate stands for,
+// or 0 if it was not sampled. 1/rate is rounded up or down at random so
+// counts stay unbiased, e.g. rate 0.4 gives 3 half of the time and 2
+// otherwise. It is decided once, at ingest, and persisted with the event so
+// replay applies the same weight.
+func sampleWeight(rate float64) uint32 {
+	if rate <= 0 || rate >= 1 {
+		return 0
+	}
+	w := 1 / rate
+	n := math.Floor(w)
+	if rand.Float64() < w-n {
+		n++
+	}
+	return uint32(min(n, math.MaxUint32))
+}
+
+// sampled decides whether a sample stage keeps an event. Hashing the user
+// keeps or drops all of a user's events together, and hashing the event id
+// otherwise gives a retried event the same decision. The stage is hashed in
+// too, so successive stages decide independently and their rates multiply.
+func sampled(stage int, event *pb.Event, rate float64) bool {
+	key := event.UserId
+	if key == "" {
+		key = event.Id
+	}
+	if key == "" {
+		return rand.Float64() < rate
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strconv.Itoa(stage)))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return float64(mix64(h.Sum64())>>11)/(1<<53) < rate
+}
+
+// mix64 spreads the bits of an FNV hash, whose high bits vary little
+// between similar keys.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// tenantOf returns the tenant of an API key. Unlisted keys are identified
+// by a fingerprint so the key itself never ends up in stored events.
+func tenantOf(tenants map[string]string, apiKey string) string {
+	if tenant, ok := tenants[apiKey]; ok {
+		return tenant
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key-" + hex.EncodeToString(sum[:4])
+}
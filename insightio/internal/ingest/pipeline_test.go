@@ -0,0 +1,333 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// within reports whether got is within tolerance, a fraction, of want.
+func within(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance*want
+}
+
+func TestProcessors(t *testing.T) {
+	peerCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4000}})
+	gatewayCtx := metadata.NewIncomingContext(peerCtx, metadata.Pairs(
+		"x-api-key", "key-1",
+		"x-forwarded-for", "203.0.113.9, 198.51.100.7",
+	))
+
+	tests := []struct {
+		name     string
+		cfg      ProcessorConfig
+		ctx      context.Context
+		event    *pb.Event
+		wantKept bool
+		wantType string
+		wantMeta map[string]string
+	}{
+		{
+			name:     "tags replace client values",
+			cfg:      ProcessorConfig{Kind: "tags", Tags: map[string]string{"env": "prod"}},
+			event:    &pb.Event{Type: "click", Metadata: map[string]string{"env": "dev", "page": "home"}},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"env": "prod", "page": "home"},
+		},
+		{
+			name:     "drop by type",
+			cfg:      ProcessorConfig{Kind: "drop", Types: []string{"heartbeat"}},
+			event:    &pb.Event{Type: "heartbeat"},
+			wantKept: false,
+		},
+		{
+			name:     "drop by metadata",
+			cfg:      ProcessorConfig{Kind: "drop", Metadata: map[string]string{"bot": "true"}},
+			event:    &pb.Event{Type: "click", Metadata: map[string]string{"bot": "true"}},
+			wantKept: false,
+		},
+		{
+			name:     "drop keeps the rest",
+			cfg:      ProcessorConfig{Kind: "drop", Types: []string{"heartbeat"}},
+			event:    &pb.Event{Type: "click"},
+			wantKept: true, wantType: "click",
+		},
+		{
+			name:     "keep",
+			cfg:      ProcessorConfig{Kind: "keep", Types: []string{"purchase"}},
+			event:    &pb.Event{Type: "click"},
+			wantKept: false,
+		},
+		{
+			name:     "rename type",
+			cfg:      ProcessorConfig{Kind: "rename_type", Renames: map[string]string{"pageview": "page_view"}},
+			event:    &pb.Event{Type: "pageview"},
+			wantKept: true, wantType: "page_view",
+		},
+		{
+			name:     "rename metadata all at once",
+			cfg:      ProcessorConfig{Kind: "rename_metadata", Renames: map[string]string{"a": "b", "b": "c"}},
+			event:    &pb.Event{Type: "click", Metadata: map[string]string{"a": "1", "b": "2"}},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"b": "1", "c": "2"},
+		},
+		{
+			name:     "peer address",
+			cfg:      ProcessorConfig{Kind: "peer_address", Key: "ip"},
+			ctx:      gatewayCtx,
+			event:    &pb.Event{Type: "click"},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"ip": "10.0.0.1"},
+		},
+		{
+			name:     "peer address forwarded by the gateway",
+			cfg:      ProcessorConfig{Kind: "peer_address", Key: "ip", Forwarded: true},
+			ctx:      gatewayCtx,
+			event:    &pb.Event{Type: "click"},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"ip": "198.51.100.7"},
+		},
+		{
+			name:     "peer address without a gateway",
+			cfg:      ProcessorConfig{Kind: "peer_address", Key: "ip", Forwarded: true},
+			ctx:      peerCtx,
+			event:    &pb.Event{Type: "click"},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"ip": "10.0.0.1"},
+		},
+		{
+			name:     "listed tenant",
+			cfg:      ProcessorConfig{Kind: "tenant", Key: "tenant", Tenants: map[string]string{"key-1": "acme"}},
+			ctx:      gatewayCtx,
+			event:    &pb.Event{Type: "click"},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"tenant": "acme"},
+		},
+		{
+			name:     "unlisted tenant",
+			cfg:      ProcessorConfig{Kind: "tenant", Key: "tenant"},
+			ctx:      gatewayCtx,
+			event:    &pb.Event{Type: "click"},
+			wantKept: true, wantType: "click",
+			wantMeta: map[string]string{"tenant": tenantOf(nil, "key-1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := newProcessor(0, tt.cfg)
+			if err != nil {
+				t.Fatalf("newProcessor: %v", err)
+			}
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			kept := processor.Process(ctx, tt.event)
+			if kept != tt.wantKept {
+				t.Fatalf("kept = %v, want %v", kept, tt.wantKept)
+			}
+			if !kept {
+				return
+			}
+			if tt.event.Type != tt.wantType {
+				t.Errorf("type %q, want %q", tt.event.Type, tt.wantType)
+			}
+			if tt.wantMeta != nil && !maps.Equal(tt.event.Metadata, tt.wantMeta) {
+				t.Errorf("metadata %v, want %v", tt.event.Metadata, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestTenantFingerprint(t *testing.T) {
+	tenant := tenantOf(nil, "secret-key")
+	if !strings.HasPrefix(tenant, "key-") || strings.Contains(tenant, "secret") {
+		t.Errorf("tenant %q does not hide the key", tenant)
+	}
+	if again := tenantOf(nil, "secret-key"); again != tenant {
+		t.Errorf("tenant %q then %q for one key", tenant, again)
+	}
+}
+
+func TestLoadPipeline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.json")
+	config := `{"processors": [
+		{"kind": "drop", "types": ["heartbeat"]},
+		{"kind": "rename_type", "renames": {"pageview": "page_view"}},
+		{"kind": "receive_time", "key": "received_at"}
+	]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadPipeline(path)
+	if err != nil {
+		t.Fatalf("LoadPipeline: %v", err)
+	}
+	if p.Len() != 3 {
+		t.Fatalf("loaded %d processors, want 3", p.Len())
+	}
+
+	event := &pb.Event{Type: "pageview"}
+	if rate, kept := p.process(context.Background(), event); !kept || rate != 1 {
+		t.Fatalf("process = %v, %v, want kept at rate 1", rate, kept)
+	}
+	if event.Type != "page_view" {
+		t.Errorf("type %q, want page_view", event.Type)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, event.Metadata["received_at"]); err != nil {
+		t.Errorf("received_at %q: %v", event.Metadata["received_at"], err)
+	}
+
+	// processors stop at the first that drops the event
+	dropped := &pb.Event{Type: "heartbeat"}
+	if _, kept := p.process(context.Background(), dropped); kept {
+		t.Error("heartbeat kept")
+	}
+	if _, ok := dropped.Metadata["received_at"]; ok {
+		t.Error("processors ran after the event was dropped")
+	}
+	if got := p.Filtered(); got != 1 {
+		t.Errorf("Filtered = %d, want 1", got)
+	}
+
+	// a missing file is an empty pipeline
+	empty, err := LoadPipeline(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("missing file: %d processors, %v", empty.Len(), err)
+	}
+}
+
+func TestNewProcessorInvalid(t *testing.T) {
+	tests := []ProcessorConfig{
+		{Kind: "unknown"},
+		{Kind: "receive_time"},
+		{Kind: "tags"},
+		{Kind: "drop"},
+		{Kind: "rename_type", Renames: map[string]string{"a": ""}},
+		{Kind: "sample", Rates: map[string]float64{"view": 0}},
+		{Kind: "sample", Rates: map[string]float64{"view": 1.5}},
+	}
+	for _, cfg := range tests {
+		if _, err := newProcessor(0, cfg); err == nil {
+			t.Errorf("newProcessor accepted %+v", cfg)
+		}
+	}
+}
+
+func TestSampleWeight(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want float64 // mean weight
+	}{
+		{1, 0},
+		{0.5, 2},
+		{0.4, 2.5},
+		{0.3, 1 / 0.3},
+		{0.01, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rate), func(t *testing.T) {
+			const draws = 100000
+			var sum float64
+			for i := 0; i < draws; i++ {
+				sum += float64(sampleWeight(tt.rate))
+			}
+			if mean := sum / draws; !within(mean, tt.want, 0.01) {
+				t.Errorf("mean weight %.3f, want %.3f", mean, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampledPerUser(t *testing.T) {
+	const users = 10000
+	keptFirst, keptBoth := 0, 0
+	for i := 0; i < users; i++ {
+		user := fmt.Sprintf("u%d", i)
+		first := sampled(0, &pb.Event{Id: "a" + user, UserId: user}, 0.5)
+		// every event of a user gets the decision of the user
+		if again := sampled(0, &pb.Event{Id: "b" + user, UserId: user}, 0.5); again != first {
+			t.Fatalf("user %s kept %v and %v by one stage", user, first, again)
+		}
+		if first {
+			keptFirst++
+			if sampled(1, &pb.Event{UserId: user}, 0.5) {
+				keptBoth++
+			}
+		}
+	}
+
+	// stages decide independently, so their rates multiply
+	if !within(float64(keptFirst), users*0.5, 0.05) {
+		t.Errorf("one stage kept %d of %d users, want about half", keptFirst, users)
+	}
+	if !within(float64(keptBoth), users*0.25, 0.05) {
+		t.Errorf("two stages kept %d of %d users, want about a quarter", keptBoth, users)
+	}
+}
+
+func TestSampledTotals(t *testing.T) {
+	const (
+		users      = 20000
+		clickUsers = 100
+		rate       = 0.3
+		value      = 2.0
+	)
+	pipeline := NewPipeline(&sampleStage{rates: map[string]float64{"view": rate}})
+	m := store.NewMetricStore(60)
+	w := NewWorker(nil, m)
+
+	ingest := func(event *pb.Event) {
+		if r, kept := pipeline.process(context.Background(), event); kept {
+			w.apply(event, sampleWeight(r))
+		}
+	}
+	for i := 0; i < users; i++ {
+		user := fmt.Sprintf("u%d", i)
+		ingest(&pb.Event{Id: "view-" + user, Type: "view", UserId: user, Value: value})
+		if i < clickUsers {
+			ingest(&pb.Event{Id: "click-" + user, Type: "click", UserId: user})
+		}
+	}
+
+	// scaled up, the kept views stand for all of them
+	if got := m.GetEventTypeCount("view"); !within(float64(got), users, 0.05) {
+		t.Errorf("counted %d views, want %d within 5%%", got, users)
+	}
+	if got := m.GetValueStats("view").Sum; !within(got, users*value, 0.05) {
+		t.Errorf("view values sum to %.0f, want %.0f within 5%%", got, users*value)
+	}
+	if got := m.GetEventTypeCount("click"); got != clickUsers {
+		t.Errorf("counted %d clicks, want %d", got, clickUsers)
+	}
+	if filtered := pipeline.Filtered(); !within(float64(filtered), users*(1-rate), 0.05) {
+		t.Errorf("filtered %d views, want about %.0f", filtered, users*(1-rate))
+	}
+
+	// sampled views are left out of unique users rather than under-counted;
+	// unique users are an estimate
+	if got := m.GetUniqueUsers(0); !within(float64(got), clickUsers, 0.05) {
+		t.Errorf("%d unique users, want about the %d who clicked", got, clickUsers)
+	}
+	if got := m.GetUniqueUsersByType("view", 0); got != 0 {
+		t.Errorf("%d unique users viewed, want 0", got)
+	}
+}
@@ -0,0 +1,306 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// OverloadPolicy decides what happens to an incoming event when the worker
+// queue is full.
+type OverloadPolicy string
+
+const (
+	OverloadBlock      OverloadPolicy = "block"       // wait for room until the request's deadline
+	OverloadReject     OverloadPolicy = "reject"      // fail the request right away
+	OverloadDropOldest OverloadPolicy = "drop_oldest" // discard the oldest queued event
+)
+
+// DefaultQueueSize is the number of events buffered between the ingest
+// service and the workers.
+const DefaultQueueSize = 1000
+
+var (
+	// ErrQueueFull is returned by the reject policy when the queue is full.
+	ErrQueueFull = errors.New("ingest queue is full")
+
+	// ErrQueueClosed is returned once the queue stops accepting events.
+	ErrQueueClosed = errors.New("ingest queue is closed")
+
+	// ErrDuplicate is returned for an event whose id was seen recently.
+	// The event was already accepted and must not be counted again.
+	ErrDuplicate = errors.New("duplicate event")
+)
+
+// QueueConfig configures a Queue. Zero values fall back to defaults.
+type QueueConfig struct {
+	Size            int // total capacity, split across partitions
+	Policy          OverloadPolicy
+	Partitions      int  // one worker goroutine drains each partition
+	PartitionByUser bool // keep each user's events on one partition, in order
+
+	Dedup *Deduper // drops events whose id was seen recently, nil disables
+}
+
+// queuedEvent is an event on its way to a worker, with its WAL sequence.
+type queuedEvent struct {
+	seq      uint64
+	event    *pb.Event
+	weight   uint32 // events it stands for if sampled, 0 if not
+	enqueued time.Time
+}
+
+// partition is the part of the queue drained by one worker.
+type partition struct {
+	events chan queuedEvent
+
+	// lock is held by the producer making room on and pushing to the
+	// partition, so its events are queued in WAL order. It is a channel so
+	// waiting for it honors deadlines.
+	lock  chan struct{}
+	space chan struct{} // signalled whenever the worker takes an event
+}
+
+// taken tells a producer waiting for room that the worker freed a slot.
+func (p *partition) taken() {
+	select {
+	case p.space <- struct{}{}:
+	default:
+	}
+}
+
+// Queue hands events persisted by the ingest service to the workers,
+// applying the overload policy when they fall behind. Each partition is
+// drained by one worker, so events on a partition are applied in WAL order.
+// A full partition only holds up producers of that partition.
+//
+// Events dropped by OverloadDropOldest stay in the WAL but are never
+// applied; a crash before the next snapshot replays them.
+type Queue struct {
+	policy          OverloadPolicy
+	partitions      []*partition
+	partitionByUser bool
+	dedup           *Deduper
+	next            atomic.Uint32 // round-robin partition for events without a user
+
+	// gate is held shared by producers from the WAL append until the event
+	// is queued, and exclusively to hold back new events.
+	gate    sync.RWMutex
+	closed  atomic.Bool   // set with gate held exclusively
+	lastSeq atomic.Uint64 // WAL sequence of the last event pushed
+
+	// events pushed but not yet applied or dropped
+	pending atomic.Int64
+	idle    chan struct{} // signalled when pending drops to zero
+
+	dropped  atomic.Int64
+	rejected atomic.Int64
+}
+
+// NewQueue creates a queue from cfg.
+func NewQueue(cfg QueueConfig) (*Queue, error) {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultQueueSize
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = OverloadBlock
+	}
+	if cfg.Partitions <= 0 {
+		cfg.Partitions = 1
+	}
+	switch cfg.Policy {
+	case OverloadBlock, OverloadReject, OverloadDropOldest:
+	default:
+		return nil, fmt.Errorf("unknown overload policy %q", cfg.Policy)
+	}
+
+	size := max(cfg.Size/cfg.Partitions, 1)
+	partitions := make([]*partition, cfg.Partitions)
+	for i := range partitions {
+		partitions[i] = &partition{
+			events: make(chan queuedEvent, size),
+			lock:   make(chan struct{}, 1),
+			space:  make(chan struct{}, 1),
+		}
+	}
+
+	return &Queue{
+		policy:          cfg.Policy,
+		partitions:      partitions,
+		partitionByUser: cfg.PartitionByUser,
+		dedup:           cfg.Dedup,
+		idle:            make(chan struct{}, 1),
+	}, nil
+}
+
+// Policy returns the queue's overload policy.
+func (q *Queue) Policy() OverloadPolicy {
+	return q.policy
+}
+
+// PartitionLens returns the number of events waiting on each partition.
+func (q *Queue) PartitionLens() []int {
+	lens := make([]int, len(q.partitions))
+	for i, p := range q.partitions {
+		lens[i] = len(p.events)
+	}
+	return lens
+}
+
+// Dropped returns the number of queued events discarded by OverloadDropOldest.
+func (q *Queue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Rejected returns the number of events refused because the queue was full
+// or the request's deadline passed while waiting for room.
+func (q *Queue) Rejected() int64 {
+	return q.rejected.Load()
+}
+
+// push appends event to the WAL and queues it for a worker. Room is made
+// before the append, so an event that is refused is never persisted.
+// The event's id is claimed before the append, so concurrent retries of one
+// event cannot both be accepted.
+func (q *Queue) push(ctx context.Context, wal *WAL, event *pb.Event, weight uint32) error {
+	if q.closed.Load() {
+		return ErrQueueClosed
+	}
+	dedup := q.dedup != nil && event.Id != ""
+	// a retry is acked even when the queue is too full to take it
+	if dedup && q.dedup.seen(event.Id, time.Now()) {
+		return ErrDuplicate
+	}
+
+	p := q.partitionFor(event)
+	select {
+	case p.lock <- struct{}{}:
+	case <-ctx.Done():
+		q.rejected.Add(1)
+		return ctx.Err()
+	}
+	defer func() { <-p.lock }()
+
+	if err := q.makeRoom(ctx, p); err != nil {
+		q.rejected.Add(1)
+		return err
+	}
+
+	q.gate.RLock()
+	defer q.gate.RUnlock()
+
+	if q.closed.Load() {
+		return ErrQueueClosed
+	}
+	now := time.Now()
+	if dedup && q.dedup.claim(event.Id, now) {
+		return ErrDuplicate
+	}
+
+	seq, err := wal.Append(event, weight)
+	if err != nil {
+		if dedup {
+			q.dedup.forget(event.Id)
+		}
+		return err
+	}
+	q.advance(seq)
+	q.pending.Add(1)
+
+	// cannot block: only the holder of p.lock sends, and makeRoom left a free slot
+	p.events <- queuedEvent{seq: seq, event: event, weight: weight, enqueued: now}
+	return nil
+}
+
+// partitionFor picks the partition for an event.
+func (q *Queue) partitionFor(event *pb.Event) *partition {
+	if len(q.partitions) == 1 {
+		return q.partitions[0]
+	}
+	if q.partitionByUser && event.UserId != "" {
+		h := fnv.New32a()
+		h.Write([]byte(event.UserId))
+		return q.partitions[h.Sum32()%uint32(len(q.partitions))]
+	}
+	return q.partitions[q.next.Add(1)%uint32(len(q.partitions))]
+}
+
+// makeRoom waits for or frees a slot on p according to the overload
+// policy. Callers hold p.lock.
+func (q *Queue) makeRoom(ctx context.Context, p *partition) error {
+	for len(p.events) == cap(p.events) {
+		switch q.policy {
+		case OverloadReject:
+			return ErrQueueFull
+
+		case OverloadDropOldest:
+			select {
+			case dropped := <-p.events:
+				// a retry of a dropped event must not be acked as a duplicate
+				if q.dedup != nil && dropped.event.Id != "" {
+					q.dedup.forget(dropped.event.Id)
+				}
+				q.dropped.Add(1)
+				q.done()
+			default:
+				// the worker took it first
+			}
+
+		default:
+			select {
+			case <-p.space:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// advance records seq as pushed. Producers of different partitions append
+// concurrently, so the highest sequence wins.
+func (q *Queue) advance(seq uint64) {
+	for {
+		last := q.lastSeq.Load()
+		if seq <= last || q.lastSeq.CompareAndSwap(last, seq) {
+			return
+		}
+	}
+}
+
+// done marks a pushed event as applied or dropped.
+func (q *Queue) done() {
+	if q.pending.Add(-1) == 0 {
+		select {
+		case q.idle <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// quiesce holds back new events until every queued event has been applied,
+// then calls fn with the WAL sequence of the last event pushed. The store
+// then reflects exactly the WAL up to that sequence.
+func (q *Queue) quiesce(fn func(lastSeq uint64)) {
+	q.gate.Lock()
+	defer q.gate.Unlock()
+
+	for q.pending.Load() > 0 {
+		<-q.idle
+	}
+	fn(q.lastSeq.Load())
+}
+
+// close stops the queue from accepting events. Events already queued are
+// still delivered.
+func (q *Queue) close() {
+	q.gate.Lock()
+	q.closed.Store(true)
+	q.gate.Unlock()
+}
@@ -0,0 +1,205 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// queuedSeqs drains a partition that no worker is reading, returning the
+// WAL sequences of its events.
+func queuedSeqs(p *partition) []uint64 {
+	var seqs []uint64
+	for len(p.events) > 0 {
+		seqs = append(seqs, (<-p.events).seq)
+	}
+	return seqs
+}
+
+func TestQueueOverloadPolicies(t *testing.T) {
+	tests := []struct {
+		policy       OverloadPolicy
+		wantErr      error
+		wantQueued   []uint64
+		wantLastSeq  uint64 // in the WAL
+		wantDropped  int64
+		wantRejected int64
+	}{
+		{OverloadReject, ErrQueueFull, []uint64{1, 2}, 2, 0, 1},
+		{OverloadDropOldest, nil, []uint64{2, 3}, 3, 1, 0},
+		{OverloadBlock, context.DeadlineExceeded, []uint64{1, 2}, 2, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			q, err := NewQueue(QueueConfig{Size: 2, Policy: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := openTestWAL(t, t.TempDir(), 100)
+			ctx := context.Background()
+
+			for i := 1; i <= 2; i++ {
+				if err := q.push(ctx, w, testEvent(i), 0); err != nil {
+					t.Fatalf("push %d: %v", i, err)
+				}
+			}
+
+			// the queue is full and no worker drains it
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			if err := q.push(ctx, w, testEvent(3), 0); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("push into a full queue: error = %v, want %v", err, tt.wantErr)
+			}
+
+			// a refused event is never persisted
+			if got := w.LastSeq(); got != tt.wantLastSeq {
+				t.Errorf("WAL LastSeq = %d, want %d", got, tt.wantLastSeq)
+			}
+			if got := queuedSeqs(q.partitions[0]); !slices.Equal(got, tt.wantQueued) {
+				t.Errorf("queued %v, want %v", got, tt.wantQueued)
+			}
+			if got := q.Dropped(); got != tt.wantDropped {
+				t.Errorf("Dropped = %d, want %d", got, tt.wantDropped)
+			}
+			if got := q.Rejected(); got != tt.wantRejected {
+				t.Errorf("Rejected = %d, want %d", got, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestQueueBlockWaitsForRoom(t *testing.T) {
+	q, err := NewQueue(QueueConfig{Size: 1, Policy: OverloadBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := openTestWAL(t, t.TempDir(), 100)
+	ctx := context.Background()
+	p := q.partitions[0]
+
+	if err := q.push(ctx, w, testEvent(1), 0); err != nil {
+		t.Fatalf("push 1: %v", err)
+	}
+
+	// a worker frees the slot while the producer waits
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-p.events
+		p.taken()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := q.push(ctx, w, testEvent(2), 0); err != nil {
+		t.Fatalf("push 2: %v", err)
+	}
+	if got := queuedSeqs(p); !slices.Equal(got, []uint64{2}) {
+		t.Errorf("queued %v, want [2]", got)
+	}
+}
+
+func TestQueueClosed(t *testing.T) {
+	q, err := NewQueue(QueueConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := openTestWAL(t, t.TempDir(), 100)
+
+	q.close()
+	if err := q.push(context.Background(), w, testEvent(1), 0); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("push error = %v, want %v", err, ErrQueueClosed)
+	}
+}
+
+func TestNewQueueUnknownPolicy(t *testing.T) {
+	if _, err := NewQueue(QueueConfig{Policy: "drop_newest"}); err == nil {
+		t.Fatal("NewQueue accepted an unknown policy")
+	}
+}
+
+func TestQueuePartitionByUser(t *testing.T) {
+	const (
+		partitions = 4
+		users      = 16
+		perUser    = 50
+	)
+	q, err := NewQueue(QueueConfig{Size: partitions * users * perUser, Partitions: partitions, PartitionByUser: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := openTestWAL(t, t.TempDir(), 10000)
+
+	// each user's events are pushed in order, users concurrently
+	var wg sync.WaitGroup
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perUser; i++ {
+				event := &pb.Event{Id: fmt.Sprintf("u%d-%d", u, i), Type: "click", UserId: fmt.Sprintf("u%d", u)}
+				if err := q.push(context.Background(), w, event, 0); err != nil {
+					t.Errorf("push %s: %v", event.Id, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	partitionOf := make(map[string]int) // user -> partition
+	next := make(map[string]int)        // user -> index of their next event
+	for i, p := range q.partitions {
+		var lastSeq uint64
+		for len(p.events) > 0 {
+			queued := <-p.events
+			// a partition holds its events in WAL order
+			if queued.seq <= lastSeq {
+				t.Errorf("partition %d: seq %d queued after %d", i, queued.seq, lastSeq)
+			}
+			lastSeq = queued.seq
+
+			user := queued.event.UserId
+			if first, ok := partitionOf[user]; ok && first != i {
+				t.Errorf("user %s is on partitions %d and %d", user, first, i)
+			}
+			partitionOf[user] = i
+			if want := fmt.Sprintf("%s-%d", user, next[user]); queued.event.Id != want {
+				t.Errorf("partition %d: got %s, want %s", i, queued.event.Id, want)
+			}
+			next[user]++
+		}
+	}
+	for u := 0; u < users; u++ {
+		if user := fmt.Sprintf("u%d", u); next[user] != perUser {
+			t.Errorf("user %s has %d events queued, want %d", user, next[user], perUser)
+		}
+	}
+}
+
+func TestQueuePartitionRoundRobin(t *testing.T) {
+	const partitions = 4
+	q, err := NewQueue(QueueConfig{Partitions: partitions, PartitionByUser: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := openTestWAL(t, t.TempDir(), 100)
+
+	// events without a user are spread evenly
+	for i := 1; i <= 2*partitions; i++ {
+		if err := q.push(context.Background(), w, testEvent(i), 0); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	for i, n := range q.PartitionLens() {
+		if n != 2 {
+			t.Errorf("partition %d holds %d events, want 2", i, n)
+		}
+	}
+}
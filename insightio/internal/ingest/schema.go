@@ -0,0 +1,299 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// SchemaMode decides what happens to an event that violates its schema.
+type SchemaMode string
+
+const (
+	SchemaStrict SchemaMode = "strict" // reject the event
+	SchemaWarn   SchemaMode = "warn"   // accept the event and count the violation
+)
+
+// ErrInvalidSchema is returned when putting a schema that cannot be enforced.
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// EventSchema declares the constraints on events of one type. Events
+// without a value (zero) are not range checked, and enumerated metadata keys
+// are only checked when present.
+type EventSchema struct {
+	Type             string              `json:"type"`
+	RequiredMetadata []string            `json:"required_metadata,omitempty"`
+	MinValue         *float64            `json:"min_value,omitempty"`
+	MaxValue         *float64            `json:"max_value,omitempty"`
+	Enums            map[string][]string `json:"enums,omitempty"` // metadata key -> allowed values
+
+	enumKeys []string // sorted keys of Enums, set by check
+}
+
+// check reports whether the schema itself is usable and prepares it for
+// validating events.
+func (s *EventSchema) check() error {
+	if s.Type == "" {
+		return errors.New("schema type is missing")
+	}
+	for _, key := range s.RequiredMetadata {
+		if key == "" {
+			return errors.New("required metadata key is empty")
+		}
+	}
+	if s.MinValue != nil && math.IsNaN(*s.MinValue) || s.MaxValue != nil && math.IsNaN(*s.MaxValue) {
+		return errors.New("value bounds must be numbers")
+	}
+	if s.MinValue != nil && s.MaxValue != nil && *s.MinValue > *s.MaxValue {
+		return fmt.Errorf("min_value %g is above max_value %g", *s.MinValue, *s.MaxValue)
+	}
+	for key, values := range s.Enums {
+		if len(values) == 0 {
+			return fmt.Errorf("enum for metadata key %q has no values", key)
+		}
+	}
+	s.enumKeys = sortedKeys(s.Enums)
+	return nil
+}
+
+// SchemaError lists every constraint an event violated.
+type SchemaError struct {
+	Type    string
+	Reasons []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("event violates schema of %q: %s", e.Type, strings.Join(e.Reasons, "; "))
+}
+
+// schemaFile is the on-disk form of the registry.
+type schemaFile struct {
+	Schemas []EventSchema `json:"schemas"`
+}
+
+// SchemaRegistry holds the schemas of event types, persisted to a JSON file.
+// Types without a schema are accepted as they are.
+type SchemaRegistry struct {
+	path string
+	mode SchemaMode
+
+	mu      sync.RWMutex
+	schemas map[string]*EventSchema
+
+	violationsMu sync.Mutex
+	rejected     map[string]int64 // event type -> events rejected
+	warned       map[string]int64 // event type -> violating events accepted
+}
+
+// LoadSchemaRegistry reads the schemas in path. A missing file yields an
+// empty registry; the file is created on the first change.
+func LoadSchemaRegistry(path string, mode SchemaMode) (*SchemaRegistry, error) {
+	if mode == "" {
+		mode = SchemaStrict
+	}
+	switch mode {
+	case SchemaStrict, SchemaWarn:
+	default:
+		return nil, fmt.Errorf("unknown schema mode %q", mode)
+	}
+
+	r := &SchemaRegistry{
+		path:     path,
+		mode:     mode,
+		schemas:  make(map[string]*EventSchema),
+		rejected: make(map[string]int64),
+		warned:   make(map[string]int64),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file schemaFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	for i := range file.Schemas {
+		schema := &file.Schemas[i]
+		if err := schema.check(); err != nil {
+			return nil, fmt.Errorf("schema %d in %s: %w", i, path, err)
+		}
+		if _, ok := r.schemas[schema.Type]; ok {
+			return nil, fmt.Errorf("duplicate schema for %q in %s", schema.Type, path)
+		}
+		r.schemas[schema.Type] = schema
+	}
+	return r, nil
+}
+
+// Mode returns how violations are handled.
+func (r *SchemaRegistry) Mode() SchemaMode {
+	return r.mode
+}
+
+// Schemas returns every schema, ordered by type.
+func (r *SchemaRegistry) Schemas() []EventSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sortedLocked()
+}
+
+// Put creates or replaces the schema of an event type and saves the registry.
+func (r *SchemaRegistry) Put(schema EventSchema) error {
+	if err := schema.check(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, existed := r.schemas[schema.Type]
+	r.schemas[schema.Type] = &schema
+	if err := r.saveLocked(); err != nil {
+		// keep memory and disk in agreement
+		if existed {
+			r.schemas[schema.Type] = prev
+		} else {
+			delete(r.schemas, schema.Type)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the schema of an event type and saves the registry. It
+// reports whether there was one.
+func (r *SchemaRegistry) Delete(eventType string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, ok := r.schemas[eventType]
+	if !ok {
+		return false, nil
+	}
+	delete(r.schemas, eventType)
+	if err := r.saveLocked(); err != nil {
+		r.schemas[eventType] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// Violations returns, per event type, the number of events rejected and the
+// number accepted despite violating their schema.
+func (r *SchemaRegistry) Violations() (rejected, warned map[string]int64) {
+	r.violationsMu.Lock()
+	defer r.violationsMu.Unlock()
+	return copyCounts(r.rejected), copyCounts(r.warned)
+}
+
+// validate returns a *SchemaError if the event violates its schema and the
+// registry is strict. In warn mode the violation is only counted.
+func (r *SchemaRegistry) validate(event *pb.Event) error {
+	r.mu.RLock()
+	schema, ok := r.schemas[event.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	reasons := schema.violations(event)
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	// warn mode only counts violations; logging each one would cost a line
+	// per event at full ingest rate
+	r.violationsMu.Lock()
+	defer r.violationsMu.Unlock()
+	if r.mode == SchemaWarn {
+		r.warned[event.Type]++
+		return nil
+	}
+	r.rejected[event.Type]++
+	return &SchemaError{Type: event.Type, Reasons: reasons}
+}
+
+// violations returns why the event does not match the schema.
+func (s *EventSchema) violations(event *pb.Event) []string {
+	var reasons []string
+	for _, key := range s.RequiredMetadata {
+		if _, ok := event.Metadata[key]; !ok {
+			reasons = append(reasons, fmt.Sprintf("missing metadata key %q", key))
+		}
+	}
+
+	// proto3 has no presence for value, so zero means "no value"
+	if event.Value != 0 {
+		if s.MinValue != nil && event.Value < *s.MinValue {
+			reasons = append(reasons, fmt.Sprintf("value %g is below minimum %g", event.Value, *s.MinValue))
+		}
+		if s.MaxValue != nil && event.Value > *s.MaxValue {
+			reasons = append(reasons, fmt.Sprintf("value %g is above maximum %g", event.Value, *s.MaxValue))
+		}
+	}
+
+	for _, key := range s.enumKeys {
+		if v, ok := event.Metadata[key]; ok && !slices.Contains(s.Enums[key], v) {
+			reasons = append(reasons, fmt.Sprintf("metadata %s=%q is not one of %s", key, v, strings.Join(s.Enums[key], ", ")))
+		}
+	}
+	return reasons
+}
+
+func (r *SchemaRegistry) sortedLocked() []EventSchema {
+	out := make([]EventSchema, 0, len(r.schemas))
+	for _, schema := range r.schemas {
+		out = append(out, *schema)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+// saveLocked writes the registry to its file, replacing it atomically.
+func (r *SchemaRegistry) saveLocked() error {
+	data, err := json.MarshalIndent(schemaFile{Schemas: r.sortedLocked()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode schemas: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("create schema dir: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write schemas: %w", err)
+	}
+	return os.Rename(tmp, r.path)
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	out := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		out[k] = v
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/auth"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// SchemaServiceServer implements the gRPC admin service for event schemas.
+// Every call needs one of the admin API keys on top of a regular key.
+type SchemaServiceServer struct {
+	pb.UnimplementedSchemaServiceServer
+	schemas *SchemaRegistry
+	admins  *auth.APIKeyValidator
+}
+
+// NewSchemaService creates the schema admin service. With no admin keys
+// every call is refused.
+func NewSchemaService(schemas *SchemaRegistry, adminKeys []string) *SchemaServiceServer {
+	return &SchemaServiceServer{
+		schemas: schemas,
+		admins:  auth.NewAPIKeyValidator(adminKeys),
+	}
+}
+
+// ListSchemas returns every schema and the enforcement mode.
+func (s *SchemaServiceServer) ListSchemas(ctx context.Context, req *pb.ListSchemasRequest) (*pb.ListSchemasResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	schemas := s.schemas.Schemas()
+	resp := &pb.ListSchemasResponse{
+		Schemas: make([]*pb.EventSchema, len(schemas)),
+		Mode:    string(s.schemas.Mode()),
+	}
+	for i := range schemas {
+		resp.Schemas[i] = schemaToProto(&schemas[i])
+	}
+	return resp, nil
+}
+
+// PutSchema creates or replaces the schema of an event type.
+func (s *SchemaServiceServer) PutSchema(ctx context.Context, schema *pb.EventSchema) (*pb.Ack, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := s.schemas.Put(schemaFromProto(schema)); err != nil {
+		if errors.Is(err, ErrInvalidSchema) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		log.Printf("Failed to save schemas: %v", err)
+		return nil, status.Error(codes.Internal, "failed to save schemas")
+	}
+
+	log.Printf("Schema for %q updated", schema.Type)
+	return &pb.Ack{Ok: true, Message: "Schema saved"}, nil
+}
+
+// DeleteSchema removes the schema of an event type.
+func (s *SchemaServiceServer) DeleteSchema(ctx context.Context, req *pb.DeleteSchemaRequest) (*pb.Ack, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.schemas.Delete(req.Type)
+	if err != nil {
+		log.Printf("Failed to save schemas: %v", err)
+		return nil, status.Error(codes.Internal, "failed to save schemas")
+	}
+	if !deleted {
+		return nil, status.Errorf(codes.NotFound, "no schema for %q", req.Type)
+	}
+
+	log.Printf("Schema for %q deleted", req.Type)
+	return &pb.Ack{Ok: true, Message: "Schema deleted"}, nil
+}
+
+// authorize checks for an admin key. The interceptors accept both regular
+// and admin keys.
+func (s *SchemaServiceServer) authorize(ctx context.Context) error {
+	if err := s.admins.ValidateAPIKey(ctx); err != nil {
+		return status.Error(codes.PermissionDenied, "admin API key required")
+	}
+	return nil
+}
+
+func schemaFromProto(p *pb.EventSchema) EventSchema {
+	schema := EventSchema{
+		Type:             p.Type,
+		RequiredMetadata: p.RequiredMetadata,
+		MinValue:         p.MinValue,
+		MaxValue:         p.MaxValue,
+	}
+	if len(p.Enums) > 0 {
+		schema.Enums = make(map[string][]string, len(p.Enums))
+		for key, allowed := range p.Enums {
+			schema.Enums[key] = allowed.GetValues()
+		}
+	}
+	return schema
+}
+
+func schemaToProto(schema *EventSchema) *pb.EventSchema {
+	p := &pb.EventSchema{
+		Type:             schema.Type,
+		RequiredMetadata: schema.RequiredMetadata,
+		MinValue:         schema.MinValue,
+		MaxValue:         schema.MaxValue,
+	}
+	if len(schema.Enums) > 0 {
+		p.Enums = make(map[string]*pb.AllowedValues, len(schema.Enums))
+		for key, values := range schema.Enums {
+			p.Enums[key] = &pb.AllowedValues{Values: values}
+		}
+	}
+	return p
+}
@@ -0,0 +1,165 @@
+package ingest
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+func purchaseSchema() EventSchema {
+	return EventSchema{
+		Type:             "purchase",
+		RequiredMetadata: []string{"currency"},
+		MinValue:         ptr(0.01),
+		MaxValue:         ptr(1000.0),
+		Enums:            map[string][]string{"currency": {"EUR", "USD"}},
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       *pb.Event
+		wantReasons int
+	}{
+		{"valid", &pb.Event{Type: "purchase", Value: 10, Metadata: map[string]string{"currency": "USD"}}, 0},
+		{"no value", &pb.Event{Type: "purchase", Metadata: map[string]string{"currency": "USD"}}, 0},
+		{"type without schema", &pb.Event{Type: "click", Value: -5}, 0},
+		{"missing metadata", &pb.Event{Type: "purchase", Value: 10}, 1},
+		{"below minimum", &pb.Event{Type: "purchase", Value: -1, Metadata: map[string]string{"currency": "EUR"}}, 1},
+		{"above maximum", &pb.Event{Type: "purchase", Value: 5000, Metadata: map[string]string{"currency": "EUR"}}, 1},
+		{"not in enum", &pb.Event{Type: "purchase", Value: 10, Metadata: map[string]string{"currency": "GBP"}}, 1},
+		{"every violation", &pb.Event{Type: "purchase", Value: 5000}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := LoadSchemaRegistry(filepath.Join(t.TempDir(), "schemas.json"), SchemaStrict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Put(purchaseSchema()); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			err = r.validate(tt.event)
+			if tt.wantReasons == 0 {
+				if err != nil {
+					t.Fatalf("validate: %v", err)
+				}
+				return
+			}
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("validate error = %v, want a *SchemaError", err)
+			}
+			if len(schemaErr.Reasons) != tt.wantReasons {
+				t.Errorf("reasons %q, want %d", schemaErr.Reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestSchemaModes(t *testing.T) {
+	invalid := &pb.Event{Type: "purchase", Value: 10}
+
+	tests := []struct {
+		mode         SchemaMode
+		wantErr      bool
+		wantRejected map[string]int64
+		wantWarned   map[string]int64
+	}{
+		{SchemaStrict, true, map[string]int64{"purchase": 2}, map[string]int64{}},
+		{SchemaWarn, false, map[string]int64{}, map[string]int64{"purchase": 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			r, err := LoadSchemaRegistry(filepath.Join(t.TempDir(), "schemas.json"), tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Put(purchaseSchema()); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := r.validate(invalid); (err != nil) != tt.wantErr {
+					t.Fatalf("validate error = %v, want error %v", err, tt.wantErr)
+				}
+			}
+			rejected, warned := r.Violations()
+			if !maps.Equal(rejected, tt.wantRejected) || !maps.Equal(warned, tt.wantWarned) {
+				t.Errorf("violations rejected %v warned %v, want %v and %v", rejected, warned, tt.wantRejected, tt.wantWarned)
+			}
+		})
+	}
+}
+
+func TestSchemaRegistryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas", "schemas.json")
+	r, err := LoadSchemaRegistry(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(purchaseSchema()); err != nil {
+		t.Fatalf("Put purchase: %v", err)
+	}
+	if err := r.Put(EventSchema{Type: "signup", RequiredMetadata: []string{"plan"}}); err != nil {
+		t.Fatalf("Put signup: %v", err)
+	}
+	if deleted, err := r.Delete("signup"); !deleted || err != nil {
+		t.Fatalf("Delete = %v, %v, want true", deleted, err)
+	}
+	if deleted, err := r.Delete("signup"); deleted || err != nil {
+		t.Fatalf("second Delete = %v, %v, want false", deleted, err)
+	}
+
+	reloaded, err := LoadSchemaRegistry(path, "")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if reloaded.Mode() != SchemaStrict {
+		t.Errorf("mode %q, want %q", reloaded.Mode(), SchemaStrict)
+	}
+	var types []string
+	for _, schema := range reloaded.Schemas() {
+		types = append(types, schema.Type)
+	}
+	if want := []string{"purchase"}; !slices.Equal(types, want) {
+		t.Errorf("reloaded schemas for %v, want %v", types, want)
+	}
+	// loaded schemas enforce their enums
+	if err := reloaded.validate(&pb.Event{Type: "purchase", Metadata: map[string]string{"currency": "GBP"}}); err == nil {
+		t.Error("reloaded schema accepted a value outside its enum")
+	}
+}
+
+func TestSchemaPutInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema EventSchema
+	}{
+		{"no type", EventSchema{RequiredMetadata: []string{"plan"}}},
+		{"empty required key", EventSchema{Type: "signup", RequiredMetadata: []string{""}}},
+		{"min above max", EventSchema{Type: "purchase", MinValue: ptr(10.0), MaxValue: ptr(1.0)}},
+		{"empty enum", EventSchema{Type: "purchase", Enums: map[string][]string{"currency": nil}}},
+	}
+
+	r, err := LoadSchemaRegistry(filepath.Join(t.TempDir(), "schemas.json"), SchemaStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if err := r.Put(tt.schema); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("%s: Put error = %v, want %v", tt.name, err, ErrInvalidSchema)
+		}
+	}
+	if got := len(r.Schemas()); got != 0 {
+		t.Errorf("%d schemas stored, want none", got)
+	}
+}
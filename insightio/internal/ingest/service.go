@@ -2,26 +2,58 @@ package ingest
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
 )
 
 // IngestServiceServer implements the gRPC ingest service.
-// It pushes incoming events into eventChan for the worker to process.
+// It writes incoming events to the WAL and then pushes them onto the queue
+// for the worker to process.
 type IngestServiceServer struct {
 	pb.UnimplementedIngestServiceServer
-	eventChan chan<- *pb.Event
+	queue *Queue
+	wal   *WAL
+	clock *EventClock
+
+	// event schemas, not enforced when nil
+	schemas *SchemaRegistry
+
+	// processors run on every event before it is validated, none when nil
+	pipeline *Pipeline
 }
 
-// NewIngestService creates a new ingest service with the event channel.
-func NewIngestService(eventChan chan<- *pb.Event) *IngestServiceServer {
+// ErrFiltered is returned for an event dropped by the processor pipeline.
+var ErrFiltered = errors.New("event dropped by filter")
+
+// NewIngestService creates a new ingest service with the worker queue, WAL
+// and the clock that assigns event times.
+func NewIngestService(queue *Queue, wal *WAL, clock *EventClock) *IngestServiceServer {
 	return &IngestServiceServer{
-		eventChan: eventChan,
+		queue: queue,
+		wal:   wal,
+		clock: clock,
 	}
 }
 
+// EnableSchemas makes the service check events against schemas before
+// accepting them. It must be called before the server starts.
+func (s *IngestServiceServer) EnableSchemas(schemas *SchemaRegistry) {
+	s.schemas = schemas
+}
+
+// EnablePipeline makes the service run every event through pipeline before
+// validating and persisting it. It must be called before the server starts.
+func (s *IngestServiceServer) EnablePipeline(pipeline *Pipeline) {
+	s.pipeline = pipeline
+}
+
 // SendEvent handles unary RPC for a single event.
 func (s *IngestServiceServer) SendEvent(ctx context.Context, event *pb.Event) (*pb.Ack, error) {
 
@@ -32,8 +64,34 @@ func (s *IngestServiceServer) SendEvent(ctx context.Context, event *pb.Event) (*
 		}, nil
 	}
 
-	// Push event to worker channel
-	s.eventChan <- event
+	weight, err := s.check(ctx, event)
+	if err != nil {
+		// dropping events is the server's choice, the client need not retry
+		if errors.Is(err, ErrFiltered) {
+			return &pb.Ack{
+				Ok:      true,
+				Message: "Event dropped by filter",
+			}, nil
+		}
+		return &pb.Ack{
+			Ok:      false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Persist and push event to worker queue
+	duplicate, err := s.enqueue(ctx, event, weight)
+	if err != nil {
+		return nil, err
+	}
+
+	// a retry of an accepted event is acked so the client stops retrying
+	if duplicate {
+		return &pb.Ack{
+			Ok:      true,
+			Message: "Duplicate event ignored",
+		}, nil
+	}
 
 	return &pb.Ack{
 		Ok:      true,
@@ -41,21 +99,28 @@ func (s *IngestServiceServer) SendEvent(ctx context.Context, event *pb.Event) (*
 	}, nil
 }
 
-// SendEventStream handles client-streaming RPC where multiple events are sent.
+// SendEventStream handles client-streaming RPC where multiple events are
+// sent. Like SendEventBatch, the ack reports which events failed, by their
+// position in the stream.
 func (s *IngestServiceServer) SendEventStream(stream pb.IngestService_SendEventStreamServer) error {
+	ack := &pb.BatchAck{}
 
-	count := 0
-
-	for {
+	for i := 0; ; i++ {
 		event, err := stream.Recv()
 
 		if err == io.EOF {
-			log.Printf("Received %d events in stream", count)
+			log.Printf("Received %d events in stream (%d duplicates ignored, %d filtered, %d rejected)",
+				ack.Accepted, ack.Duplicates, ack.Filtered, ack.Rejected)
 
 			// Send final ack and close stream
 			return stream.SendAndClose(&pb.Ack{
-				Ok:      true,
-				Message: "Stream received successfully",
+				Ok:         true,
+				Message:    "Stream received successfully",
+				Accepted:   ack.Accepted,
+				Rejected:   ack.Rejected,
+				Duplicates: ack.Duplicates,
+				Filtered:   ack.Filtered,
+				Errors:     ack.Errors,
 			})
 		}
 
@@ -64,14 +129,106 @@ func (s *IngestServiceServer) SendEventStream(stream pb.IngestService_SendEventS
 			return err
 		}
 
-		// Validate event
-		if event.Type == "" {
-			log.Println("Skipping event with missing type")
-			continue
+		s.ingest(stream.Context(), ack, i, event)
+	}
+}
+
+// SendEventBatch handles unary RPC for a batch of events. Each event is
+// validated and persisted on its own; the ack reports which ones failed.
+func (s *IngestServiceServer) SendEventBatch(ctx context.Context, batch *pb.EventBatch) (*pb.BatchAck, error) {
+	ack := &pb.BatchAck{}
+	for i, event := range batch.GetEvents() {
+		s.ingest(ctx, ack, i, event)
+	}
+
+	if ack.Rejected > 0 {
+		log.Printf("Received batch of %d events, %d rejected", len(batch.GetEvents()), ack.Rejected)
+	}
+	return ack, nil
+}
+
+// ingest validates and persists the i-th event of a batch or stream and
+// records the outcome in ack.
+func (s *IngestServiceServer) ingest(ctx context.Context, ack *pb.BatchAck, i int, event *pb.Event) {
+	reject := func(reason string, retryable bool) {
+		ack.Rejected++
+		ack.Errors = append(ack.Errors, &pb.EventError{
+			Index:     int32(i),
+			Reason:    reason,
+			Retryable: retryable,
+		})
+	}
+
+	if event.GetType() == "" {
+		reject("event type is missing", false)
+		return
+	}
+	weight, err := s.check(ctx, event)
+	if err != nil {
+		if errors.Is(err, ErrFiltered) {
+			ack.Filtered++
+			return
+		}
+		reject(err.Error(), false)
+		return
+	}
+
+	duplicate, err := s.enqueue(ctx, event, weight)
+	if err != nil {
+		// apart from oversized events, the queue or server state is at fault
+		reject(status.Convert(err).Message(), status.Code(err) != codes.InvalidArgument)
+		return
+	}
+	ack.Accepted++
+	if duplicate {
+		ack.Duplicates++
+	}
+}
+
+// check runs an event that has a type through the pipeline, validates it
+// against its schema and assigns the time it is bucketed by, returning why
+// it was refused. weight is the number of events it stands for if sampled,
+// 0 if not.
+func (s *IngestServiceServer) check(ctx context.Context, event *pb.Event) (weight uint32, err error) {
+	rate := 1.0
+	if s.pipeline != nil {
+		var kept bool
+		if rate, kept = s.pipeline.process(ctx, event); !kept {
+			return 0, ErrFiltered
 		}
+	}
+	if s.schemas != nil {
+		if err := s.schemas.validate(event); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.clock.stamp(event, time.Now()); err != nil {
+		return 0, err
+	}
+	return sampleWeight(rate), nil
+}
 
-		// Push event to worker channel
-		s.eventChan <- event
-		count++
+// enqueue appends the event to the WAL and hands it to the worker,
+// returning a gRPC status error if the event was not accepted. duplicate is
+// set, with a nil error, for a recently seen event that was skipped.
+// The event is only acked once this returns a nil error.
+func (s *IngestServiceServer) enqueue(ctx context.Context, event *pb.Event, weight uint32) (duplicate bool, err error) {
+	err = s.queue.push(ctx, s.wal, event, weight)
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, ErrDuplicate):
+		return true, nil
+	case errors.Is(err, ErrQueueFull):
+		return false, status.Error(codes.ResourceExhausted, "ingest queue is full, retry later")
+	case errors.Is(err, ErrRecordTooLarge):
+		return false, status.Error(codes.InvalidArgument, "event is too large to persist")
+	case errors.Is(err, ErrQueueClosed):
+		return false, status.Error(codes.Unavailable, "server is shutting down")
+	case ctx.Err() != nil:
+		return false, status.FromContextError(ctx.Err()).Err()
+	default:
+		log.Printf("Failed to persist event: %v", err)
+		return false, status.Error(codes.Unavailable, "failed to persist event")
 	}
 }
@@ -0,0 +1,130 @@
+package ingest
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// DefaultTailBuffer is the number of events buffered per tail subscriber
+// before further events are dropped for it.
+const DefaultTailBuffer = 256
+
+// TailFilter selects the events a tail subscriber receives. Empty fields
+// match everything.
+type TailFilter struct {
+	Type       string
+	UserID     string
+	Metadata   map[string]string // every pair must be present on the event
+	SampleRate float64           // fraction of matching events delivered, 0 or 1 means all
+}
+
+func (f TailFilter) match(event *pb.Event) bool {
+	if f.Type != "" && event.Type != f.Type {
+		return false
+	}
+	if f.UserID != "" && event.UserId != f.UserID {
+		return false
+	}
+	for k, v := range f.Metadata {
+		if got, ok := event.Metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	if f.SampleRate > 0 && f.SampleRate < 1 && rand.Float64() >= f.SampleRate {
+		return false
+	}
+	return true
+}
+
+// Tail fans out live events to debugging subscribers. Publishing never
+// blocks: a subscriber that falls behind loses events instead of slowing
+// down ingestion.
+type Tail struct {
+	mu      sync.RWMutex
+	subs    map[*TailSubscription]struct{}
+	dropped atomic.Int64
+}
+
+// NewTail returns a tail with no subscribers.
+func NewTail() *Tail {
+	return &Tail{subs: make(map[*TailSubscription]struct{})}
+}
+
+// TailSubscription receives the events matching its filter.
+type TailSubscription struct {
+	tail    *Tail
+	filter  TailFilter
+	events  chan *pb.Event
+	dropped atomic.Int64
+}
+
+// Subscribe registers a subscriber buffering up to buffer events.
+// Callers must Close the subscription when done.
+func (t *Tail) Subscribe(filter TailFilter, buffer int) *TailSubscription {
+	if buffer <= 0 {
+		buffer = DefaultTailBuffer
+	}
+	sub := &TailSubscription{
+		tail:   t,
+		filter: filter,
+		events: make(chan *pb.Event, buffer),
+	}
+
+	t.mu.Lock()
+	t.subs[sub] = struct{}{}
+	t.mu.Unlock()
+	return sub
+}
+
+// Publish offers an event to every subscriber whose filter matches it.
+// Published events are shared between subscribers and must not be modified.
+func (t *Tail) Publish(event *pb.Event) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for sub := range t.subs {
+		if !sub.filter.match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// slow consumer; drop rather than back-pressure the worker
+			sub.dropped.Add(1)
+			t.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of events dropped across all subscribers.
+func (t *Tail) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+// Subscribers returns the number of active subscribers.
+func (t *Tail) Subscribers() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.subs)
+}
+
+// Events returns the channel the subscriber's events arrive on.
+func (s *TailSubscription) Events() <-chan *pb.Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the subscriber fell behind.
+func (s *TailSubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unregisters the subscriber. The events channel is left open so a
+// concurrent Publish never sends on a closed channel.
+func (s *TailSubscription) Close() {
+	s.tail.mu.Lock()
+	delete(s.tail.subs, s)
+	s.tail.mu.Unlock()
+}
@@ -0,0 +1,595 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// FsyncPolicy controls when WAL writes are flushed to stable storage.
+type FsyncPolicy string
+
+const (
+	FsyncAlways   FsyncPolicy = "always"   // fsync before every append returns
+	FsyncInterval FsyncPolicy = "interval" // fsync periodically in the background
+	FsyncNever    FsyncPolicy = "never"    // leave flushing to the OS
+)
+
+const (
+	walSegmentExt = ".wal"
+
+	// segment header: magic, format version
+	walMagic             = "IWAL"
+	walVersion           = 1
+	walSegmentHeaderSize = 4 + 4
+
+	// record header: payload length, crc32 of the rest, sequence number,
+	// sample weight (0 if not sampled)
+	walHeaderSize = 4 + 4 + 8 + 4
+
+	// walMaxRecordSize bounds a record regardless of the segment size, so a
+	// damaged length is caught before it is trusted with an allocation.
+	walMaxRecordSize = 16 << 20
+)
+
+var (
+	ErrWALClosed      = errors.New("wal is closed")
+	ErrCorruptRecord  = errors.New("wal record is corrupt")
+	ErrRecordTooLarge = errors.New("wal record is too large")
+	ErrWALFormat      = errors.New("wal segment has an unknown format")
+
+	// errTornSegmentHeader is a segment cut short before its header was
+	// written in full.
+	errTornSegmentHeader = fmt.Errorf("torn segment header: %w", ErrCorruptRecord)
+)
+
+var (
+	crcTable          = crc32.MakeTable(crc32.Castagnoli)
+	defaultSegmentCap = int64(64 << 20)
+)
+
+// WALConfig configures the write-ahead log.
+type WALConfig struct {
+	Dir           string
+	SegmentBytes  int64
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+// WAL is an append-only, segment-rotated log of accepted events.
+// Each record carries a monotonically increasing sequence number so that
+// replay can resume from any point.
+type WAL struct {
+	mu       sync.Mutex
+	syncMu   sync.Mutex // serializes fsyncs made outside mu
+	cfg      WALConfig
+	file     *os.File
+	segSize  int64
+	lastSeq  uint64
+	synced   uint64 // last record known to be on stable storage
+	closed   bool
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// OpenWAL opens (or creates) the WAL in cfg.Dir, recovering the last
+// sequence number and trimming any torn record at the tail.
+func OpenWAL(cfg WALConfig) (*WAL, error) {
+	if cfg.SegmentBytes <= 0 {
+		cfg.SegmentBytes = defaultSegmentCap
+	}
+	if cfg.Fsync == "" {
+		cfg.Fsync = FsyncInterval
+	}
+	if cfg.FsyncInterval <= 0 {
+		cfg.FsyncInterval = time.Second
+	}
+
+	switch cfg.Fsync {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("unknown wal fsync policy %q", cfg.Fsync)
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create wal dir: %w", err)
+	}
+
+	w := &WAL{
+		cfg:      cfg,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+
+	segments, err := w.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(segments) == 0 {
+		if err := w.openSegment(1); err != nil {
+			return nil, err
+		}
+	} else {
+		// Earlier segments are immutable; only the last one can have a torn tail.
+		last := segments[len(segments)-1]
+		lastSeq, validSize, err := scanSegment(w.segmentPath(last))
+		if err != nil {
+			return nil, err
+		}
+		if lastSeq == 0 {
+			lastSeq = last - 1
+		}
+		w.lastSeq = lastSeq
+
+		f, err := os.OpenFile(w.segmentPath(last), os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open wal segment: %w", err)
+		}
+		if err := f.Truncate(validSize); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("truncate torn wal tail: %w", err)
+		}
+		if _, err := f.Seek(validSize, io.SeekStart); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("seek wal segment: %w", err)
+		}
+		if validSize == 0 {
+			// created by a crash before its header was written
+			if err := writeSegmentHeader(f); err != nil {
+				f.Close()
+				return nil, err
+			}
+			validSize = walSegmentHeaderSize
+		}
+		w.file = f
+		w.segSize = validSize
+	}
+
+	if cfg.Fsync == FsyncInterval {
+		go w.syncLoop()
+	} else {
+		close(w.doneChan)
+	}
+
+	return w, nil
+}
+
+// Append writes an event and the number of events it stands for to the log
+// and returns its sequence number. With FsyncAlways the record is on stable
+// storage when Append returns.
+func (w *WAL) Append(event *pb.Event, weight uint32) (uint64, error) {
+	payload, err := proto.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("marshal event: %w", err)
+	}
+	recSize := int64(walHeaderSize + len(payload))
+	if recSize > walMaxRecordSize || walSegmentHeaderSize+recSize > w.cfg.SegmentBytes {
+		return 0, ErrRecordTooLarge
+	}
+
+	seq, err := w.write(payload, weight, recSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// The fsync runs outside mu so appends go on meanwhile; the ones that
+	// arrive during it share the next fsync.
+	if w.cfg.Fsync == FsyncAlways {
+		if err := w.syncThrough(seq); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+// write appends one record to the active segment and returns its sequence.
+func (w *WAL) write(payload []byte, weight uint32, recSize int64) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrWALClosed
+	}
+
+	if w.segSize > walSegmentHeaderSize && w.segSize+recSize > w.cfg.SegmentBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	seq := w.lastSeq + 1
+
+	record := make([]byte, recSize)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint64(record[8:16], seq)
+	binary.BigEndian.PutUint32(record[16:20], weight)
+	copy(record[walHeaderSize:], payload)
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(record[8:], crcTable))
+
+	// The record goes to the OS in one write so a process crash cannot lose
+	// it; only the fsync is subject to the policy.
+	if _, err := w.file.Write(record); err != nil {
+		return 0, fmt.Errorf("write wal record: %w", err)
+	}
+
+	w.segSize += recSize
+	w.lastSeq = seq
+	return seq, nil
+}
+
+// LastSeq returns the sequence number of the most recently appended record.
+func (w *WAL) LastSeq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastSeq
+}
+
+// SkipTo moves the sequence past seq, so the next record is seq+1. It is
+// used when the WAL is behind a snapshot, e.g. after its directory was lost,
+// so new records are not mistaken for ones the snapshot already covers.
+// The skip is recorded by starting a new segment.
+func (w *WAL) SkipTo(seq uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWALClosed
+	}
+	if seq <= w.lastSeq {
+		return nil
+	}
+
+	if w.segSize > walSegmentHeaderSize {
+		w.lastSeq = seq
+		return w.rotate()
+	}
+
+	// the active segment is empty, so replace it rather than seal it
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close wal segment: %w", err)
+	}
+	if err := os.Remove(w.file.Name()); err != nil {
+		return fmt.Errorf("remove wal segment: %w", err)
+	}
+	w.lastSeq = seq
+	return w.openSegment(seq + 1)
+}
+
+// Replay calls fn for every record with a sequence number greater than
+// afterSeq, in order.
+func (w *WAL) Replay(afterSeq uint64, fn func(seq uint64, weight uint32, event *pb.Event) error) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWALClosed
+	}
+	segments, err := w.segments()
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for i, first := range segments {
+		// skip segments entirely covered by afterSeq
+		if i+1 < len(segments) && segments[i+1]-1 <= afterSeq {
+			continue
+		}
+		// only the last segment can have been cut short by a crash
+		var nextSeq uint64
+		if i+1 < len(segments) {
+			nextSeq = segments[i+1]
+		}
+		if err := w.replaySegment(first, nextSeq, afterSeq, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TruncateBefore deletes sealed segments whose records all have a sequence
+// number at or below seq. The active segment is never removed.
+func (w *WAL) TruncateBefore(seq uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i+1]-1 > seq {
+			break
+		}
+		if err := os.Remove(w.segmentPath(segments[i])); err != nil {
+			return fmt.Errorf("remove wal segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Sync fsyncs the active segment.
+func (w *WAL) Sync() error {
+	return w.syncThrough(w.LastSeq())
+}
+
+// syncThrough fsyncs until record seq is on stable storage. Callers that
+// wait together share one fsync.
+func (w *WAL) syncThrough(seq uint64) error {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	w.mu.Lock()
+	if w.synced >= seq {
+		w.mu.Unlock()
+		return nil
+	}
+	file, last := w.file, w.lastSeq
+	w.mu.Unlock()
+
+	err := file.Sync()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		// a rotation or Close may have synced and closed the file meanwhile
+		if w.synced >= seq {
+			return nil
+		}
+		return fmt.Errorf("fsync wal: %w", err)
+	}
+	w.synced = max(w.synced, last)
+	return nil
+}
+
+// Close syncs and closes the active segment.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stopChan)
+	w.mu.Unlock()
+
+	<-w.doneChan
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.syncLocked(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+func (w *WAL) syncLocked() error {
+	if w.synced >= w.lastSeq {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("fsync wal: %w", err)
+	}
+	w.synced = w.lastSeq
+	return nil
+}
+
+func (w *WAL) syncLoop() {
+	defer close(w.doneChan)
+
+	ticker := time.NewTicker(w.cfg.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Sync(); err != nil {
+				log.Printf("WAL sync failed: %v", err)
+			}
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// rotate seals the active segment and starts a new one.
+func (w *WAL) rotate() error {
+	if err := w.syncLocked(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close wal segment: %w", err)
+	}
+	return w.openSegment(w.lastSeq + 1)
+}
+
+// openSegment creates a new segment whose first record will be firstSeq.
+func (w *WAL) openSegment(firstSeq uint64) error {
+	f, err := os.OpenFile(w.segmentPath(firstSeq), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("create wal segment: %w", err)
+	}
+	if err := writeSegmentHeader(f); err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.segSize = walSegmentHeaderSize
+	return nil
+}
+
+func writeSegmentHeader(f *os.File) error {
+	var header [walSegmentHeaderSize]byte
+	copy(header[0:4], walMagic)
+	binary.BigEndian.PutUint32(header[4:8], walVersion)
+	if _, err := f.Write(header[:]); err != nil {
+		return fmt.Errorf("write wal segment header: %w", err)
+	}
+	return nil
+}
+
+func (w *WAL) segmentPath(firstSeq uint64) string {
+	return filepath.Join(w.cfg.Dir, fmt.Sprintf("%020d%s", firstSeq, walSegmentExt))
+}
+
+// segments returns the first sequence number of every segment, ascending.
+func (w *WAL) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(w.cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("read wal dir: %w", err)
+	}
+
+	var out []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, walSegmentExt) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, walSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, first)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+// scanSegment returns the last valid sequence number in a segment and the
+// byte offset just past the last valid record, or zero if the segment
+// header is incomplete.
+func scanSegment(path string) (uint64, int64, error) {
+	var lastSeq uint64
+	offset := int64(walSegmentHeaderSize)
+
+	err := readSegment(path, func(seq uint64, _ uint32, _ []byte, end int64) error {
+		lastSeq = seq
+		offset = end
+		return nil
+	})
+	if errors.Is(err, errTornSegmentHeader) {
+		return 0, 0, nil
+	}
+	if err != nil && !errors.Is(err, ErrCorruptRecord) {
+		return 0, 0, err
+	}
+	return lastSeq, offset, nil
+}
+
+// replaySegment replays the records of the segment starting at firstSeq.
+// nextSeq is the first record of the following segment, or zero if this is
+// the last one.
+func (w *WAL) replaySegment(firstSeq, nextSeq, afterSeq uint64, fn func(seq uint64, weight uint32, event *pb.Event) error) error {
+	path := w.segmentPath(firstSeq)
+	lastSeq := firstSeq - 1
+	err := readSegment(path, func(seq uint64, weight uint32, payload []byte, _ int64) error {
+		lastSeq = seq
+		if seq <= afterSeq {
+			return nil
+		}
+		event := &pb.Event{}
+		if err := proto.Unmarshal(payload, event); err != nil {
+			return fmt.Errorf("decode wal record %d: %w", seq, err)
+		}
+		return fn(seq, weight, event)
+	})
+	if errors.Is(err, ErrCorruptRecord) {
+		if nextSeq != 0 {
+			// a sealed segment was fsynced before the next one was started,
+			// so this is damage on disk, not a crash mid-write
+			return fmt.Errorf("wal segment %s: records %d to %d are lost: %w", filepath.Base(path), lastSeq+1, nextSeq-1, err)
+		}
+		// a torn tail from a crash mid-write; everything before it is intact
+		log.Printf("WAL segment %s has a torn tail, stopping replay there", filepath.Base(path))
+		return nil
+	}
+	return err
+}
+
+// readSegment iterates over the records in a segment, stopping with
+// ErrCorruptRecord at the first incomplete or checksum-failing record.
+// Records longer than walMaxRecordSize, which Append never writes, are
+// corrupt. A segment without a known header fails with ErrWALFormat rather
+// than being read, and possibly truncated, as damaged records.
+func readSegment(path string, fn func(seq uint64, weight uint32, payload []byte, end int64) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open wal segment: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat wal segment: %w", err)
+	}
+	fileSize := info.Size()
+
+	r := bufio.NewReader(f)
+	var segHeader [walSegmentHeaderSize]byte
+	if _, err := io.ReadFull(r, segHeader[:]); err != nil {
+		return errTornSegmentHeader
+	}
+	if string(segHeader[0:4]) != walMagic {
+		return fmt.Errorf("%w: %s has no segment header", ErrWALFormat, filepath.Base(path))
+	}
+	if version := binary.BigEndian.Uint32(segHeader[4:8]); version != walVersion {
+		return fmt.Errorf("%w: %s has version %d, want %d", ErrWALFormat, filepath.Base(path), version, walVersion)
+	}
+
+	offset := int64(walSegmentHeaderSize)
+	var header [walHeaderSize]byte
+
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return ErrCorruptRecord
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		want := binary.BigEndian.Uint32(header[4:8])
+		seq := binary.BigEndian.Uint64(header[8:16])
+		weight := binary.BigEndian.Uint32(header[16:20])
+
+		// check the length before trusting it with an allocation
+		recSize := int64(walHeaderSize) + int64(size)
+		if recSize > walMaxRecordSize || offset+recSize > fileSize {
+			return ErrCorruptRecord
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return ErrCorruptRecord
+		}
+
+		crc := crc32.Update(0, crcTable, header[8:])
+		crc = crc32.Update(crc, crcTable, payload)
+		if crc != want {
+			return ErrCorruptRecord
+		}
+
+		offset += recSize
+		if err := fn(seq, weight, payload, offset); err != nil {
+			return err
+		}
+	}
+}
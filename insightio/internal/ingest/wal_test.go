@@ -0,0 +1,368 @@
+package ingest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+)
+
+// testEvent returns the i-th event of a test; all have the same size.
+func testEvent(i int) *pb.Event {
+	return &pb.Event{Id: fmt.Sprintf("e%04d", i), Type: "click"}
+}
+
+// testRecordSize is the size on disk of a record holding a testEvent.
+var testRecordSize = int64(walHeaderSize + proto.Size(testEvent(0)))
+
+func openTestWAL(t *testing.T, dir string, segmentRecords int) *WAL {
+	t.Helper()
+	w, err := OpenWAL(WALConfig{
+		Dir:          dir,
+		SegmentBytes: walSegmentHeaderSize + int64(segmentRecords)*testRecordSize,
+		Fsync:        FsyncNever,
+	})
+	if err != nil {
+		t.Fatalf("OpenWAL: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+// appendEvents appends events first to last, each weighted by its index.
+func appendEvents(t *testing.T, w *WAL, first, last int) {
+	t.Helper()
+	for i := first; i <= last; i++ {
+		seq, err := w.Append(testEvent(i), uint32(i))
+		if err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+		if seq != uint64(i) {
+			t.Fatalf("Append %d got seq %d", i, seq)
+		}
+	}
+}
+
+// replayed returns the sequences replayed after afterSeq, checking each
+// record against the event appended with it.
+func replayed(t *testing.T, w *WAL, afterSeq uint64) ([]uint64, error) {
+	t.Helper()
+	var seqs []uint64
+	err := w.Replay(afterSeq, func(seq uint64, weight uint32, event *pb.Event) error {
+		if want := testEvent(int(seq)); !proto.Equal(event, want) {
+			t.Errorf("record %d holds %v, want %v", seq, event, want)
+		}
+		if weight != uint32(seq) {
+			t.Errorf("record %d has weight %d, want %d", seq, weight, seq)
+		}
+		seqs = append(seqs, seq)
+		return nil
+	})
+	return seqs, err
+}
+
+func seqRange(first, last int) []uint64 {
+	var seqs []uint64
+	for i := first; i <= last; i++ {
+		seqs = append(seqs, uint64(i))
+	}
+	return seqs
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+walSegmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	return files
+}
+
+func TestWALReplay(t *testing.T) {
+	tests := []struct {
+		name           string
+		segmentRecords int
+		events         int
+		afterSeq       uint64
+		want           []uint64
+		wantSegments   int
+	}{
+		{"one segment", 100, 10, 0, seqRange(1, 10), 1},
+		{"one segment after seq", 100, 10, 4, seqRange(5, 10), 1},
+		{"rotated", 3, 10, 0, seqRange(1, 10), 4},
+		{"rotated after seq", 3, 10, 4, seqRange(5, 10), 4},
+		{"rotated after segment boundary", 3, 10, 6, seqRange(7, 10), 4},
+		{"after last", 3, 10, 10, nil, 4},
+		{"empty", 3, 0, 0, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := openTestWAL(t, dir, tt.segmentRecords)
+			appendEvents(t, w, 1, tt.events)
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if got := len(segmentFiles(t, dir)); got != tt.wantSegments {
+				t.Errorf("got %d segments, want %d", got, tt.wantSegments)
+			}
+
+			// reopening recovers the sequence from disk
+			w = openTestWAL(t, dir, tt.segmentRecords)
+			if got := w.LastSeq(); got != uint64(tt.events) {
+				t.Errorf("LastSeq after reopen = %d, want %d", got, tt.events)
+			}
+			got, err := replayed(t, w, tt.afterSeq)
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWALDamagedTail(t *testing.T) {
+	const events = 5
+	lastRecord := walSegmentHeaderSize + (events-1)*testRecordSize
+
+	tests := []struct {
+		name   string
+		damage func(data []byte) []byte
+	}{
+		{"torn header", func(data []byte) []byte {
+			return data[:lastRecord+walHeaderSize/2]
+		}},
+		{"torn payload", func(data []byte) []byte {
+			return data[:len(data)-1]
+		}},
+		{"bad checksum", func(data []byte) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		}},
+		{"huge length", func(data []byte) []byte {
+			binary.BigEndian.PutUint32(data[lastRecord:], 0xffffffff)
+			return data
+		}},
+		{"length past the end", func(data []byte) []byte {
+			binary.BigEndian.PutUint32(data[lastRecord:], uint32(testRecordSize))
+			return data
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := openTestWAL(t, dir, 100)
+			appendEvents(t, w, 1, events)
+			w.Close()
+
+			path := segmentFiles(t, dir)[0]
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.damage(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// the damaged record is dropped and its sequence reused
+			w = openTestWAL(t, dir, 100)
+			if got := w.LastSeq(); got != events-1 {
+				t.Fatalf("LastSeq = %d, want %d", got, events-1)
+			}
+			appendEvents(t, w, events, events+1)
+
+			got, err := replayed(t, w, 0)
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if want := seqRange(1, events+1); !slices.Equal(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWALCorruptSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+	w := openTestWAL(t, dir, 3)
+	appendEvents(t, w, 1, 7)
+	w.Close()
+
+	// damage the second record of the first segment
+	path := segmentFiles(t, dir)[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[walSegmentHeaderSize+2*testRecordSize-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w = openTestWAL(t, dir, 3)
+	if got := w.LastSeq(); got != 7 {
+		t.Errorf("LastSeq = %d, want 7", got)
+	}
+	got, err := replayed(t, w, 0)
+	if !errors.Is(err, ErrCorruptRecord) {
+		t.Fatalf("Replay error = %v, want %v", err, ErrCorruptRecord)
+	}
+	if want := []uint64{1}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v before the damage, want %v", got, want)
+	}
+
+	// replay starting past the damaged segment is unaffected
+	got, err = replayed(t, w, 3)
+	if err != nil {
+		t.Fatalf("Replay after 3: %v", err)
+	}
+	if want := seqRange(4, 7); !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestWALSkipTo(t *testing.T) {
+	tests := []struct {
+		name   string
+		events int // appended before the skip
+	}{
+		{"empty active segment", 0},
+		{"records in active segment", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := openTestWAL(t, dir, 100)
+			appendEvents(t, w, 1, tt.events)
+			if err := w.SkipTo(10); err != nil {
+				t.Fatalf("SkipTo: %v", err)
+			}
+			appendEvents(t, w, 11, 12)
+			w.Close()
+
+			// the skip survives a reopen
+			w = openTestWAL(t, dir, 100)
+			if got := w.LastSeq(); got != 12 {
+				t.Errorf("LastSeq = %d, want 12", got)
+			}
+			got, err := replayed(t, w, 0)
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			want := append(seqRange(1, tt.events), 11, 12)
+			if !slices.Equal(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWALRecordTooLarge(t *testing.T) {
+	tests := []struct {
+		name           string
+		segmentRecords int
+		value          string
+	}{
+		{"larger than a segment", 1, "a value that does not fit"},
+		{"larger than the format allows", 1 << 20, strings.Repeat("x", walMaxRecordSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := openTestWAL(t, t.TempDir(), tt.segmentRecords)
+
+			event := testEvent(1)
+			event.Metadata = map[string]string{"k": tt.value}
+			if _, err := w.Append(event, 1); !errors.Is(err, ErrRecordTooLarge) {
+				t.Fatalf("Append error = %v, want %v", err, ErrRecordTooLarge)
+			}
+			appendEvents(t, w, 1, 1)
+		})
+	}
+}
+
+func TestWALTornSegmentHeader(t *testing.T) {
+	dir := t.TempDir()
+	w := openTestWAL(t, dir, 3)
+	appendEvents(t, w, 1, 3)
+	w.Close()
+
+	// a crash right after the next segment was created
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", 4, walSegmentExt))
+	if err := os.WriteFile(path, []byte(walMagic[:2]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w = openTestWAL(t, dir, 3)
+	if got := w.LastSeq(); got != 3 {
+		t.Fatalf("LastSeq = %d, want 3", got)
+	}
+	appendEvents(t, w, 4, 5)
+	w.Close()
+
+	w = openTestWAL(t, dir, 3)
+	got, err := replayed(t, w, 0)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if want := seqRange(1, 5); !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestWALUnknownFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"no header", []byte{0, 0, 0, 10, 0, 0, 0, 0}},
+		{"newer version", append([]byte(walMagic), 0, 0, 0, walVersion+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := openTestWAL(t, dir, 100)
+			appendEvents(t, w, 1, 3)
+			w.Close()
+
+			path := segmentFiles(t, dir)[0]
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			copy(data, tt.header)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// the segment is refused rather than truncated as damaged
+			_, err = OpenWAL(WALConfig{Dir: dir, Fsync: FsyncNever})
+			if !errors.Is(err, ErrWALFormat) {
+				t.Fatalf("OpenWAL error = %v, want %v", err, ErrWALFormat)
+			}
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(after) != len(data) {
+				t.Errorf("segment changed from %d to %d bytes", len(data), len(after))
+			}
+		})
+	}
+}
@@ -1,57 +1,266 @@
 package ingest
 
 import (
+	"errors"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
 
 	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
 )
 
-// Worker pulls full Event objects from eventChan and updates the MetricStore.
+// Worker pulls full Event objects from the queue and updates the MetricStore.
+// It runs one goroutine per queue partition.
 type Worker struct {
-	eventChan   <-chan *pb.Event   // receives events
+	queue       *Queue             // receives events
 	metricStore *store.MetricStore // reference to metric store
 	stopChan    chan struct{}      // for graceful shutdown
+	wg          sync.WaitGroup     // worker and snapshot goroutines
+
+	// periodic snapshots, disabled when snapshotPath is empty
+	wal              *WAL
+	snapshotPath     string
+	snapshotInterval time.Duration
+
+	// live events are offered to tail subscribers, disabled when nil
+	tail *Tail
+
+	// time from enqueue until an event has been applied
+	latencyMu sync.Mutex
+	latency   *store.LatencyHist
 }
 
-// NewWorker creates a worker bound to eventChan and metric store.
-func NewWorker(eventChan <-chan *pb.Event, store *store.MetricStore) *Worker {
+// NewWorker creates a worker bound to the queue and metric store.
+func NewWorker(queue *Queue, metricStore *store.MetricStore) *Worker {
 	return &Worker{
-		eventChan:   eventChan,
-		metricStore: store,
+		queue:       queue,
+		metricStore: metricStore,
 		stopChan:    make(chan struct{}),
+		latency:     store.NewLatencyHist(store.DefaultBuckets),
 	}
 }
 
-// Start launches the worker loop in a goroutine.
-func (w *Worker) Start() {
-	go func() {
-		log.Println("Worker started")
-
-		for {
-			select {
-
-			// event received from ingest service
-			case event, ok := <-w.eventChan:
-				if !ok {
-					log.Println("eventChan closed, worker stopping")
-					return
-				}
-				if event != nil {
-					w.metricStore.AddEvent(event.Type)
-				}
-
-			// stop signal received
-			case <-w.stopChan:
-				log.Println("Worker stopped")
-				return
-			}
+// EnableSnapshots makes the worker write a store snapshot to path every
+// interval and on Stop, truncating WAL segments the snapshot covers.
+// It must be called before Start.
+func (w *Worker) EnableSnapshots(path string, interval time.Duration, wal *WAL) {
+	w.wal = wal
+	w.snapshotPath = path
+	w.snapshotInterval = interval
+}
+
+// EnableTail makes the worker publish every live event to tail after
+// applying it. Replayed events are not published. It must be called before Start.
+func (w *Worker) EnableTail(tail *Tail) {
+	w.tail = tail
+}
+
+// Replay applies every WAL event after afterSeq to the MetricStore.
+// afterSeq is the WAL sequence already reflected in the store, e.g. from a
+// snapshot. It must be called before Start so replayed events are not
+// interleaved with live ones.
+//
+// With deduplication enabled, the ids of all events still in the WAL are
+// remembered, including those the snapshot covers, so their retries are
+// recognized after a restart. Ids of events in segments already truncated
+// by a snapshot are not.
+func (w *Worker) Replay(wal *WAL, afterSeq uint64) error {
+	dedup := w.queue.dedup
+	if dedup != nil && afterSeq > 0 {
+		w.rememberIDs(wal, afterSeq)
+	}
+
+	count := 0
+	now := time.Now()
+	err := wal.Replay(afterSeq, func(seq uint64, weight uint32, event *pb.Event) error {
+		w.apply(event, weight)
+		if dedup != nil && event.Id != "" {
+			dedup.remember(event.Id, now)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// a WAL behind the snapshot (e.g. a wiped WAL dir) must not reuse
+	// sequence numbers the snapshot covers, or the next replay skips them
+	if afterSeq > wal.LastSeq() {
+		log.Printf("WAL ends at record %d, before the snapshot at %d; continuing from the snapshot", wal.LastSeq(), afterSeq)
+		if err := wal.SkipTo(afterSeq); err != nil {
+			return err
 		}
-	}()
+	}
+	w.queue.lastSeq.Store(wal.LastSeq())
+
+	log.Printf("Replayed %d events from WAL", count)
+	return nil
 }
 
-// Stop gracefully shuts down the worker.
+// errReplayedIDs stops rememberIDs at the end of the snapshot.
+var errReplayedIDs = errors.New("ids up to the snapshot remembered")
+
+// rememberIDs remembers the ids of WAL events up to afterSeq, which the
+// snapshot already counts. A damaged record only costs the ids after it,
+// so it is logged rather than failing the replay.
+func (w *Worker) rememberIDs(wal *WAL, afterSeq uint64) {
+	now := time.Now()
+	err := wal.Replay(0, func(seq uint64, _ uint32, event *pb.Event) error {
+		if seq > afterSeq {
+			return errReplayedIDs
+		}
+		if event.Id != "" {
+			w.queue.dedup.remember(event.Id, now)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errReplayedIDs) {
+		log.Printf("Failed to remember event ids from WAL: %v", err)
+	}
+}
+
+// Start launches one worker goroutine per queue partition, plus one taking
+// periodic snapshots if enabled.
+func (w *Worker) Start() {
+	for _, p := range w.queue.partitions {
+		w.wg.Add(1)
+		go w.run(p)
+	}
+
+	if w.snapshotPath != "" && w.snapshotInterval > 0 {
+		w.wg.Add(1)
+		go w.snapshotLoop()
+	}
+
+	log.Printf("Started %d worker(s)", len(w.queue.partitions))
+}
+
+// Stop refuses new events, waits until the queued ones have been applied
+// and writes a final snapshot.
 func (w *Worker) Stop() {
+	w.queue.close()
+	w.queue.quiesce(func(uint64) {})
+
 	close(w.stopChan)
+	w.wg.Wait()
+
+	w.snapshot()
+	log.Println("Worker stopped")
+}
+
+// ProcessingLatency returns statistics on the time from enqueue until an
+// event has been applied, in milliseconds.
+func (w *Worker) ProcessingLatency() store.LatencyStats {
+	w.latencyMu.Lock()
+	defer w.latencyMu.Unlock()
+
+	return store.LatencyStats{
+		Min:       w.latency.GetMin(),
+		Max:       w.latency.GetMax(),
+		Avg:       w.latency.Avg(),
+		Median:    w.latency.GetMedian(),
+		P95:       w.latency.GetPercentile(95),
+		P99:       w.latency.GetPercentile(99),
+		TotalReqs: w.latency.Count(),
+	}
+}
+
+// run applies the events of one partition in order.
+func (w *Worker) run(p *partition) {
+	defer w.wg.Done()
+
+	for {
+		select {
+
+		// event received from ingest service
+		case queued := <-p.events:
+			p.taken()
+			w.apply(queued.event, queued.weight)
+			if w.tail != nil {
+				w.tail.Publish(queued.event)
+			}
+			w.observe(time.Since(queued.enqueued))
+			w.queue.done()
+
+		// stop signal received
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+func (w *Worker) snapshotLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.snapshot()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+func (w *Worker) observe(d time.Duration) {
+	w.latencyMu.Lock()
+	w.latency.Observe(d)
+	w.latencyMu.Unlock()
+}
+
+// apply updates the MetricStore with a single event, bucketed by its
+// event time. A sampled event, one with a weight, counts as weight events
+// but is left out of unique users, funnels and sessions: those follow
+// users, and the users a sample keeps cannot stand in for the rest.
+func (w *Worker) apply(event *pb.Event, weight uint32) {
+	t := eventTime(event, time.Now())
+	sampled := weight > 0
+	n := int64(max(weight, 1))
+	w.metricStore.AddEventsAt(event.Type, n, t)
+
+	// proto3 has no presence for value, so zero means "no value"
+	hasValue := event.Value != 0
+	if hasValue {
+		w.metricStore.RecordValuesAt(event.Type, event.Value, n, t)
+	}
+	if event.UserId != "" && !sampled {
+		w.metricStore.RecordUserAt(event.Type, event.UserId, t)
+		w.metricStore.RecordFunnelEventAt(event.Type, event.UserId, t)
+		w.metricStore.RecordSessionEventAt(event.UserId, t)
+	}
+	if len(event.Metadata) > 0 {
+		w.metricStore.RecordDimensionsN(event.Type, event.Metadata, n, event.Value, hasValue)
+	}
+}
+
+// snapshot writes the store to disk and drops WAL segments it covers.
+// Ingestion pauses while queued events are applied and the store is copied,
+// so the snapshot matches the WAL exactly up to its sequence.
+func (w *Worker) snapshot() {
+	if w.snapshotPath == "" {
+		return
+	}
+
+	var snap *store.Snapshot
+	w.queue.quiesce(func(lastSeq uint64) {
+		snap = w.metricStore.Snapshot()
+		snap.WALSeq = lastSeq
+	})
+
+	if err := store.WriteSnapshot(w.snapshotPath, snap); err != nil {
+		log.Printf("Failed to write snapshot: %v", err)
+		return
+	}
+
+	if w.wal != nil {
+		if err := w.wal.TruncateBefore(snap.WALSeq); err != nil {
+			log.Printf("Failed to truncate WAL: %v", err)
+		}
+	}
 }
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// breakdownValues maps the metric kinds that can be grouped by a dimension
+// to the value they report for one dimension value.
+var breakdownValues = map[string]func(store.DimensionStats) float64{
+	"events":      func(ds store.DimensionStats) float64 { return float64(ds.Count) },
+	"value_sum":   func(ds store.DimensionStats) float64 { return ds.Values.Sum },
+	"value_count": func(ds store.DimensionStats) float64 { return float64(ds.Values.Count) },
+	"value_avg":   func(ds store.DimensionStats) float64 { return ds.Values.Avg },
+	"value_min":   func(ds store.DimensionStats) float64 { return ds.Values.Min },
+	"value_max":   func(ds store.DimensionStats) float64 { return ds.Values.Max },
+}
+
+// getBreakdown answers a GetMetrics request with group_by set. Each requested
+// per-type metric (e.g. value_sum:purchase) is returned once per dimension
+// value, labelled with that value. Breakdowns are all-time.
+func (s *MetricsServiceServer) getBreakdown(req *pb.GetMetricsRequest) (*pb.MetricResponse, error) {
+	if !s.store.HasDimension(req.GroupBy) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown dimension %q", req.GroupBy)
+	}
+	if len(req.MetricsNames) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "group_by requires metrics_names")
+	}
+	if req.WindowSeconds != 0 {
+		return nil, status.Error(codes.InvalidArgument, "group_by does not support window_seconds")
+	}
+
+	resp := &pb.MetricResponse{}
+
+	for _, name := range req.MetricsNames {
+		kind, eventType, ok := strings.Cut(name, ":")
+		valueOf, supported := breakdownValues[kind]
+		if !ok || eventType == "" || !supported {
+			return nil, status.Errorf(codes.InvalidArgument, "metric %s cannot be grouped by %s", name, req.GroupBy)
+		}
+
+		for _, ds := range s.store.GetDimensionBreakdown(eventType, req.GroupBy) {
+			metric := s.makeMetric(name, valueOf(ds))
+			metric.Labels = map[string]string{req.GroupBy: ds.Value}
+			resp.Metrics = append(resp.Metrics, metric)
+		}
+	}
+
+	return resp, nil
+}
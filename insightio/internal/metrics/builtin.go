@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
+)
+
+// RegisterStoreMetrics registers the metrics backed by the MetricStore.
+func RegisterStoreMetrics(r *Registry, st *store.MetricStore) error {
+	builtins := []struct {
+		def      Definition
+		provider Provider
+	}{
+		{
+			Definition{Name: "total_events", Description: "Events ingested since the server started.", Unit: "events"},
+			Value(func(q Query) float64 { return float64(st.GetTotalEvents()) }),
+		},
+		{
+			Definition{Name: "events_per_window", Description: "Events ingested within the window.", Unit: "events", Windowed: true},
+			Value(func(q Query) float64 { return float64(st.GetEventsInWindow(q.Window)) }),
+		},
+		{
+			Definition{Name: "total_throughput", Description: "gRPC requests per second across all methods within the window.", Unit: "requests/s", Windowed: true},
+			Value(func(q Query) float64 { return st.GetTotalThroughputInWindow(q.Window) }),
+		},
+		{
+			Definition{Name: "total_error_rate", Description: "Share of gRPC requests that failed, all-time unless a window is requested.", Unit: "percent", Windowed: true},
+			Value(func(q Query) float64 { return errorRate(st, q) }),
+		},
+		{
+			Definition{Name: "unique_users", Description: "Estimated distinct users within the window.", Unit: "users", Windowed: true},
+			Value(func(q Query) float64 { return float64(st.GetUniqueUsers(q.Window)) }),
+		},
+		{
+			Definition{Name: "endpoint_requests", Description: "gRPC requests since the server started, by method.", Unit: "requests", Labels: []string{"method"}},
+			func(q Query) []Sample {
+				return methodSamples(st, func(m store.MethodMetrics) float64 { return float64(m.Requests) })
+			},
+		},
+		{
+			Definition{Name: "endpoint_errors", Description: "Failed gRPC requests since the server started, by method.", Unit: "requests", Labels: []string{"method"}},
+			func(q Query) []Sample {
+				return methodSamples(st, func(m store.MethodMetrics) float64 { return float64(m.Errors) })
+			},
+		},
+		{
+			Definition{Name: "events", Description: "Events of the type ingested since the server started.", Unit: "events", PerType: true},
+			Value(func(q Query) float64 { return float64(st.GetEventTypeCount(q.EventType)) }),
+		},
+		{
+			Definition{Name: "events_per_window", Description: "Events of the type ingested within the window.", Unit: "events", PerType: true, Windowed: true},
+			Value(func(q Query) float64 { return float64(st.GetEventTypeCountInWindow(q.EventType, q.Window)) }),
+		},
+		{
+			Definition{Name: "unique_users", Description: "Estimated distinct users that sent events of the type within the window.", Unit: "users", PerType: true, Windowed: true},
+			Value(func(q Query) float64 { return float64(st.GetUniqueUsersByType(q.EventType, q.Window)) }),
+		},
+	}
+
+	for _, b := range builtins {
+		if err := r.Register(b.def, b.provider); err != nil {
+			return err
+		}
+	}
+
+	// value_sum:purchase is all-time, value_sum_per_window:purchase is windowed
+	for _, vf := range valueFields {
+		field := vf.field
+		all := Definition{Name: vf.name, Description: vf.description + " of the type's event values since the server started.", PerType: true}
+		if err := r.Register(all, Value(func(q Query) float64 {
+			return field(st.GetValueStats(q.EventType))
+		})); err != nil {
+			return err
+		}
+
+		windowed := Definition{Name: vf.name + "_per_window", Description: vf.description + " of the type's event values within the window.", PerType: true, Windowed: true}
+		if err := r.Register(windowed, Value(func(q Query) float64 {
+			return field(st.GetValueStatsInWindow(q.EventType, q.Window))
+		})); err != nil {
+			return err
+		}
+	}
+
+	if st.TracksSessions() {
+		return registerSessionMetrics(r, st)
+	}
+	return nil
+}
+
+// registerSessionMetrics registers the metrics of user sessions. Those of
+// ended sessions are all-time unless a window is requested.
+func registerSessionMetrics(r *Registry, st *store.MetricStore) error {
+	stats := func(q Query) store.SessionStats {
+		if q.Window == 0 {
+			return st.GetSessionStats()
+		}
+		return st.GetSessionStatsInWindow(q.Window)
+	}
+
+	sessions := []struct {
+		def      Definition
+		provider Provider
+	}{
+		{
+			Definition{Name: "active_sessions", Description: "User sessions with an event within the inactivity gap.", Unit: "sessions"},
+			Value(func(q Query) float64 { return float64(st.GetSessionStats().Active) }),
+		},
+		{
+			Definition{Name: "total_sessions", Description: "User sessions started since the server started.", Unit: "sessions"},
+			Value(func(q Query) float64 { return float64(st.GetSessionStats().Started) }),
+		},
+		{
+			Definition{Name: "sessions_per_window", Description: "User sessions started within the window.", Unit: "sessions", Windowed: true},
+			Value(func(q Query) float64 { return float64(st.GetSessionStatsInWindow(q.Window).Started) }),
+		},
+		{
+			Definition{Name: "avg_session_duration", Description: "Average time from the first to the last event of ended sessions.", Unit: "seconds", Windowed: true},
+			Value(func(q Query) float64 { return stats(q).AvgDuration }),
+		},
+		{
+			Definition{Name: "avg_events_per_session", Description: "Average number of events in ended sessions.", Unit: "events", Windowed: true},
+			Value(func(q Query) float64 { return stats(q).AvgEvents }),
+		},
+		{
+			Definition{Name: "session_bounce_rate", Description: "Share of ended sessions with a single event.", Unit: "percent", Windowed: true},
+			Value(func(q Query) float64 { return stats(q).BounceRate }),
+		},
+		{
+			Definition{Name: "untracked_session_events", Description: "Events left out of sessions because too many users had a session open.", Unit: "events"},
+			Value(func(q Query) float64 { return float64(st.UntrackedSessionEvents()) }),
+		},
+	}
+
+	for _, b := range sessions {
+		if err := r.Register(b.def, b.provider); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueFields lists the value aggregates reported per event type.
+var valueFields = []struct {
+	name        string
+	description string
+	field       func(store.ValueStats) float64
+}{
+	{"value_sum", "Sum", func(vs store.ValueStats) float64 { return vs.Sum }},
+	{"value_count", "Number", func(vs store.ValueStats) float64 { return float64(vs.Count) }},
+	{"value_avg", "Average", func(vs store.ValueStats) float64 { return vs.Avg }},
+	{"value_min", "Minimum", func(vs store.ValueStats) float64 { return vs.Min }},
+	{"value_max", "Maximum", func(vs store.ValueStats) float64 { return vs.Max }},
+}
+
+// errorRate returns the error rate within the requested window, or the
+// all-time error rate when no window was requested.
+func errorRate(st *store.MetricStore, q Query) float64 {
+	if q.Window == 0 {
+		return st.GetTotalErrorRate()
+	}
+	return st.GetTotalErrorRateInWindow(q.Window)
+}
+
+// methodSamples reports one sample per method, labelled with the method name.
+func methodSamples(st *store.MetricStore, valueOf func(store.MethodMetrics) float64) []Sample {
+	methods := st.GetAllMethodMetrics()
+	samples := make([]Sample, 0, len(methods))
+	for _, m := range methods {
+		samples = append(samples, Sample{
+			Labels: map[string]string{"method": m.Method},
+			Value:  valueOf(m),
+		})
+	}
+	return samples
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetEndpointStats returns the top_k slowest endpoints with their request,
+// error and latency statistics.
+func (s *MetricsServiceServer) GetEndpointStats(ctx context.Context, req *pb.GetEndpointStatsRequest) (*pb.EndpointStatsResponse, error) {
+	if req.TopK < 0 {
+		return nil, status.Error(codes.InvalidArgument, "top_k must not be negative")
+	}
+
+	// 0 means every endpoint
+	k := int(req.TopK)
+	if k == 0 {
+		k = math.MaxInt
+	}
+
+	resp := &pb.EndpointStatsResponse{}
+	for _, endpoint := range s.store.GetTopSlowestEndpoints(k) {
+		resp.Endpoints = append(resp.Endpoints, s.endpointStats(endpoint.Method))
+	}
+
+	return resp, nil
+}
+
+// GetLatencyStats returns the request, error and latency statistics of one endpoint.
+func (s *MetricsServiceServer) GetLatencyStats(ctx context.Context, req *pb.GetLatencyStatsRequest) (*pb.EndpointStats, error) {
+	if req.Method == "" {
+		return nil, status.Error(codes.InvalidArgument, "method is required")
+	}
+	if !s.store.HasMethod(req.Method) {
+		return nil, status.Errorf(codes.NotFound, "no requests recorded for method %s", req.Method)
+	}
+
+	return s.endpointStats(req.Method), nil
+}
+
+// endpointStats collects the statistics of one method from the store.
+func (s *MetricsServiceServer) endpointStats(method string) *pb.EndpointStats {
+	latency := s.store.GetLatencyStats(method)
+
+	stats := &pb.EndpointStats{
+		Method:     method,
+		Requests:   s.store.GetRequestCount(method),
+		Errors:     s.store.GetErrorCount(method),
+		ErrorRate:  s.store.GetErrorRate(method),
+		Throughput: s.store.GetThroughput(method),
+		AvgMs:      latency.Avg,
+		MinMs:      latency.Min,
+		MaxMs:      latency.Max,
+		P50Ms:      latency.Median,
+		P95Ms:      latency.P95,
+		P99Ms:      latency.P99,
+	}
+
+	for upper, count := range s.store.GetLatencyDistribution(method) {
+		stats.Distribution = append(stats.Distribution, &pb.LatencyBucket{
+			UpperMs: upper,
+			Count:   count,
+		})
+	}
+	sort.Slice(stats.Distribution, func(i, j int) bool {
+		return stats.Distribution[i].UpperMs < stats.Distribution[j].UpperMs
+	})
+
+	return stats
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetFunnel returns how many of the attempts at a funnel started within the
+// window reached each step, and the conversion rates between them.
+func (s *MetricsServiceServer) GetFunnel(ctx context.Context, req *pb.GetFunnelRequest) (*pb.FunnelResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	window, err := s.requestWindow(req.WindowSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	report, ok := s.store.GetFunnel(req.Name, window)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no funnel named %q", req.Name)
+	}
+
+	resp := &pb.FunnelResponse{
+		Name:          req.Name,
+		WithinSeconds: int32(report.Definition.Within.Seconds()),
+		Skipped:       report.Skipped,
+		Timestamp:     timestamppb.Now(),
+	}
+
+	entered := report.Steps[0].Count
+	for i, step := range report.Steps {
+		out := &pb.FunnelStep{
+			EventType:      step.EventType,
+			Count:          step.Count,
+			ConversionRate: percent(step.Count, entered),
+		}
+		if i == 0 {
+			out.StepConversionRate = out.ConversionRate
+		} else {
+			out.StepConversionRate = percent(step.Count, report.Steps[i-1].Count)
+		}
+		resp.Steps = append(resp.Steps, out)
+	}
+
+	return resp, nil
+}
+
+// percent returns n as a percentage of total, or 0 if total is 0.
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusHandler serves the MetricStore, along with the registry metrics
+// marked for export, in the Prometheus text exposition format. Windowed
+// store metrics are reported over the store's sliding window.
+func PrometheusHandler(store *store.MetricStore, registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", prometheusContentType)
+
+		bw := bufio.NewWriter(w)
+		writePrometheus(bw, store)
+		writeRegistry(bw, registry)
+		if err := bw.Flush(); err != nil {
+			log.Printf("Failed to write Prometheus metrics: %v", err)
+		}
+	})
+}
+
+func writePrometheus(w *bufio.Writer, st *store.MetricStore) {
+	methods := st.GetAllMethodMetrics()
+
+	writeHeader(w, "insightio_requests_total", "counter", "Total gRPC requests by method.")
+	for _, m := range methods {
+		fmt.Fprintf(w, "insightio_requests_total{method=%s} %d\n", quoteLabel(m.Method), m.Requests)
+	}
+
+	writeHeader(w, "insightio_request_errors_total", "counter", "Total gRPC requests that returned an error, by method.")
+	for _, m := range methods {
+		fmt.Fprintf(w, "insightio_request_errors_total{method=%s} %d\n", quoteLabel(m.Method), m.Errors)
+	}
+
+	writeHeader(w, "insightio_request_duration_seconds", "histogram", "gRPC request latency by method.")
+	for _, m := range methods {
+		method := quoteLabel(m.Method)
+
+		// The store's last bucket also holds overflow, so it is only
+		// reported through +Inf.
+		cumulative := int64(0)
+		for i := 0; i < len(m.BucketBounds)-1; i++ {
+			cumulative += m.BucketCounts[i]
+			fmt.Fprintf(w, "insightio_request_duration_seconds_bucket{method=%s,le=%q} %d\n",
+				method, formatSeconds(m.BucketBounds[i]), cumulative)
+		}
+		fmt.Fprintf(w, "insightio_request_duration_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", method, m.LatencyCount)
+		fmt.Fprintf(w, "insightio_request_duration_seconds_sum{method=%s} %s\n", method, formatSeconds(m.LatencySumMs))
+		fmt.Fprintf(w, "insightio_request_duration_seconds_count{method=%s} %d\n", method, m.LatencyCount)
+	}
+
+	writeHeader(w, "insightio_events_total", "counter", "Total ingested events by type.")
+	counts := st.GetEventTypeCounts()
+	types := make([]string, 0, len(counts))
+	for eventType := range counts {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	for _, eventType := range types {
+		fmt.Fprintf(w, "insightio_events_total{type=%s} %d\n", quoteLabel(eventType), counts[eventType])
+	}
+
+	values := make(map[string]store.ValueStats, len(types))
+	for _, eventType := range types {
+		if stats := st.GetValueStats(eventType); stats.Count > 0 {
+			values[eventType] = stats
+		}
+	}
+	writeHeader(w, "insightio_event_value", "summary", "Values of ingested events by type.")
+	for _, eventType := range types {
+		if stats, ok := values[eventType]; ok {
+			label := quoteLabel(eventType)
+			fmt.Fprintf(w, "insightio_event_value_sum{type=%s} %s\n", label, formatFloat(stats.Sum))
+			fmt.Fprintf(w, "insightio_event_value_count{type=%s} %d\n", label, stats.Count)
+		}
+	}
+	writeHeader(w, "insightio_event_value_min", "gauge", "Smallest value of ingested events by type.")
+	for _, eventType := range types {
+		if stats, ok := values[eventType]; ok {
+			fmt.Fprintf(w, "insightio_event_value_min{type=%s} %s\n", quoteLabel(eventType), formatFloat(stats.Min))
+		}
+	}
+	writeHeader(w, "insightio_event_value_max", "gauge", "Largest value of ingested events by type.")
+	for _, eventType := range types {
+		if stats, ok := values[eventType]; ok {
+			fmt.Fprintf(w, "insightio_event_value_max{type=%s} %s\n", quoteLabel(eventType), formatFloat(stats.Max))
+		}
+	}
+
+	writeHeader(w, "insightio_unique_users", "gauge", "Estimated distinct users within the sliding window.")
+	fmt.Fprintf(w, "insightio_unique_users %d\n", st.GetUniqueUsers(0))
+
+	writeHeader(w, "insightio_event_unique_users", "gauge", "Estimated distinct users within the sliding window by event type.")
+	for _, eventType := range types {
+		fmt.Fprintf(w, "insightio_event_unique_users{type=%s} %d\n", quoteLabel(eventType), st.GetUniqueUsersByType(eventType, 0))
+	}
+}
+
+// writeRegistry writes the registry metrics marked for export. Counters get
+// the conventional _total suffix.
+func writeRegistry(w *bufio.Writer, registry *Registry) {
+	if registry == nil {
+		return
+	}
+	for _, m := range registry.exported() {
+		name := "insightio_" + m.def.Name
+		if m.def.Prometheus == "counter" {
+			name += "_total"
+		}
+		writeHeader(w, name, m.def.Prometheus, m.def.Description)
+
+		for _, sample := range m.provider(Query{}) {
+			fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(m.def.Labels, sample.Labels), formatFloat(sample.Value))
+		}
+	}
+}
+
+// formatLabels writes the labels of a sample in the order they are declared.
+func formatLabels(keys []string, labels map[string]string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if v, ok := labels[key]; ok {
+			pairs = append(pairs, key+"="+quoteLabel(v))
+		}
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func writeHeader(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// formatSeconds converts milliseconds to a seconds value.
+func formatSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'g', -1, 64)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// quoteLabel quotes a label value, escaping backslash, quote and newline as
+// the exposition format requires.
+func quoteLabel(v string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range v {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
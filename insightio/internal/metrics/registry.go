@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Definition describes a metric in the registry catalog.
+type Definition struct {
+	Name        string
+	Description string
+	Unit        string
+	Labels      []string // label keys set on the reported samples
+	PerType     bool     // requested as name:<type>, with * for every event type
+	Windowed    bool     // honors window_seconds
+
+	// Prometheus is the type ("counter" or "gauge") the metric is exported
+	// to Prometheus as; empty leaves it out. Only plain metrics are exported.
+	Prometheus string
+}
+
+// Query is what a provider is asked to compute.
+type Query struct {
+	EventType string        // set for per-type metrics
+	Window    time.Duration // zero means the server's default window
+}
+
+// Sample is one value reported by a provider. Metrics that report several
+// series tell them apart by their labels.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Provider computes the samples of a metric.
+type Provider func(q Query) []Sample
+
+// Value adapts a single-valued function to a Provider.
+func Value(fn func(q Query) float64) Provider {
+	return func(q Query) []Sample {
+		return []Sample{{Value: fn(q)}}
+	}
+}
+
+type registeredMetric struct {
+	def      Definition
+	provider Provider
+}
+
+// Registry holds the metrics that can be requested by name. Plain and
+// per-type metrics live in separate namespaces, so events_per_window and
+// events_per_window:<type> can both exist.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*registeredMetric
+	perType map[string]*registeredMetric
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]*registeredMetric),
+		perType: make(map[string]*registeredMetric),
+	}
+}
+
+// Register adds a metric to the registry.
+func (r *Registry) Register(def Definition, provider Provider) error {
+	if def.Name == "" || strings.Contains(def.Name, ":") {
+		return fmt.Errorf("invalid metric name %q", def.Name)
+	}
+	if provider == nil {
+		return fmt.Errorf("metric %s has no provider", def.Name)
+	}
+	switch def.Prometheus {
+	case "", "counter", "gauge":
+	default:
+		return fmt.Errorf("metric %s has unknown Prometheus type %q", def.Name, def.Prometheus)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	namespace := r.metrics
+	if def.PerType {
+		namespace = r.perType
+	}
+	if _, ok := namespace[def.Name]; ok {
+		return fmt.Errorf("metric %s already registered", def.Name)
+	}
+	namespace[def.Name] = &registeredMetric{def: def, provider: provider}
+	return nil
+}
+
+// List returns the catalog of registered metrics sorted by name, plain
+// metrics before per-type ones of the same name.
+func (r *Registry) List() []Definition {
+	r.mu.RLock()
+	defs := make([]Definition, 0, len(r.metrics)+len(r.perType))
+	for _, m := range r.metrics {
+		defs = append(defs, m.def)
+	}
+	for _, m := range r.perType {
+		defs = append(defs, m.def)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Name != defs[j].Name {
+			return defs[i].Name < defs[j].Name
+		}
+		return !defs[i].PerType
+	})
+	return defs
+}
+
+// exported returns the plain metrics exported to Prometheus, sorted by name.
+func (r *Registry) exported() []*registeredMetric {
+	r.mu.RLock()
+	var out []*registeredMetric
+	for _, m := range r.metrics {
+		if m.def.Prometheus != "" {
+			out = append(out, m)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].def.Name < out[j].def.Name })
+	return out
+}
+
+// lookup finds the metric a requested name refers to and returns it with
+// the requested event type, which is empty for plain metrics.
+func (r *Registry) lookup(name string) (*registeredMetric, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	kind, eventType, perType := strings.Cut(name, ":")
+	if !perType {
+		if m, ok := r.metrics[name]; ok {
+			return m, "", nil
+		}
+		if _, ok := r.perType[name]; ok {
+			return nil, "", status.Errorf(codes.InvalidArgument, "metric %s requires an event type, e.g. %s:<type>", name, name)
+		}
+		return nil, "", status.Errorf(codes.InvalidArgument, "unknown metric %q", name)
+	}
+
+	m, ok := r.perType[kind]
+	if !ok {
+		return nil, "", status.Errorf(codes.InvalidArgument, "unknown metric %q", name)
+	}
+	if eventType == "" {
+		return nil, "", status.Errorf(codes.InvalidArgument, "metric %s requires an event type", name)
+	}
+	return m, eventType, nil
+}
@@ -3,97 +3,190 @@ package metrics
 import (
 	"context"
 	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/auth"
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/ingest"
 	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/metrics/store"
 	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Push interval of SubscribeMetrics when the client does not choose one,
+// and the default bounds on what it may choose.
+const (
+	DefaultSubscribeInterval    = 2 * time.Second
+	DefaultSubscribeMinInterval = 500 * time.Millisecond
+	DefaultSubscribeMaxInterval = time.Minute
+)
+
 // MetricsServiceServer implements the metrics service.
 type MetricsServiceServer struct {
 	pb.UnimplementedMetricsServiceServer
-	store *store.MetricStore
+	store    *store.MetricStore
+	registry *Registry
+
+	minInterval time.Duration
+	maxInterval time.Duration
+
+	tail       *ingest.Tail          // live events for TailEvents, nil when disabled
+	tailAdmins *auth.APIKeyValidator // keys allowed to call TailEvents
+
+	// closed on shutdown to end long-lived streams
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+// NewMetricsService returns a new metrics service instance. Metrics are
+// requested by the names registered in registry.
+func NewMetricsService(store *store.MetricStore, registry *Registry) *MetricsServiceServer {
+	return &MetricsServiceServer{
+		store:       store,
+		registry:    registry,
+		minInterval: DefaultSubscribeMinInterval,
+		maxInterval: DefaultSubscribeMaxInterval,
+		shutdown:    make(chan struct{}),
+	}
+}
+
+// Shutdown ends every SubscribeMetrics and TailEvents stream with
+// UNAVAILABLE, so a graceful server stop does not wait on them and clients
+// reconnect to another instance.
+func (s *MetricsServiceServer) Shutdown() {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
 }
 
-// NewMetricsService returns a new metrics service instance.
-func NewMetricsService(store *store.MetricStore) *MetricsServiceServer {
-	return &MetricsServiceServer{store: store}
+// errShuttingDown ends streams when the server shuts down.
+var errShuttingDown = status.Error(codes.Unavailable, "server is shutting down")
+
+// SetSubscribeIntervals bounds the push interval SubscribeMetrics clients may
+// ask for. Non-positive values keep the current bound.
+func (s *MetricsServiceServer) SetSubscribeIntervals(minInterval, maxInterval time.Duration) {
+	if minInterval > 0 {
+		s.minInterval = minInterval
+	}
+	if maxInterval > 0 {
+		s.maxInterval = maxInterval
+	}
+	if s.maxInterval < s.minInterval {
+		s.maxInterval = s.minInterval
+	}
 }
 
 // GetMetrics returns a snapshot of requested metrics.
 func (s *MetricsServiceServer) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.MetricResponse, error) {
 
-	resp := &pb.MetricResponse{}
+	if req.GroupBy != "" {
+		return s.getBreakdown(req)
+	}
+
+	window, err := s.requestWindow(req.WindowSeconds)
+	if err != nil {
+		return nil, err
+	}
 
 	// if client requested no specific metrics, return defaults
-	if len(req.MetricsNames) == 0 {
-		resp.Metrics = append(resp.Metrics,
-			s.makeMetric("total_events", float64(s.store.GetTotalEvents())),
-			s.makeMetric("events_per_window", float64(s.store.GetEventsPerWindow())),
-			s.makeMetric("total_throughput", s.store.GetTotalThroughput()),
-			s.makeMetric("total_error_rate", s.store.GetTotalErrorRate()),
-		)
-		return resp, nil
-	}
-
-	// return only requested metrics
-	for _, name := range req.MetricsNames {
-
-		switch name {
-
-		case "total_events":
-			resp.Metrics = append(resp.Metrics,
-				s.makeMetric("total_events", float64(s.store.GetTotalEvents())),
-			)
-
-		case "events_per_window":
-			resp.Metrics = append(resp.Metrics,
-				s.makeMetric("events_per_window", float64(s.store.GetEventsPerWindow())),
-			)
-
-		case "total_throughput":
-			resp.Metrics = append(resp.Metrics,
-				s.makeMetric("total_throughput", s.store.GetTotalThroughput()),
-			)
-
-		case "total_error_rate":
-			resp.Metrics = append(resp.Metrics,
-				s.makeMetric("total_error_rate", s.store.GetTotalErrorRate()),
-			)
-
-		default:
-			log.Printf("Unknown metric requested: %s", name)
+	names := req.MetricsNames
+	if len(names) == 0 {
+		names = []string{"total_events", "events_per_window", "total_throughput", "total_error_rate"}
+	}
+
+	resp := &pb.MetricResponse{}
+	for _, name := range names {
+		metrics, err := s.resolve(name, window)
+		if err != nil {
+			return nil, err
 		}
+		resp.Metrics = append(resp.Metrics, metrics...)
 	}
 
 	return resp, nil
 }
 
-// SubscribeMetrics streams metrics in real time.
+// SubscribeMetrics streams the requested metrics every interval. With a
+// change threshold, a metric is only pushed when it moved by more than the
+// threshold since it was last pushed; with on_change and no threshold, when
+// it changed at all.
 func (s *MetricsServiceServer) SubscribeMetrics(req *pb.GetMetricsRequest, stream pb.MetricsService_SubscribeMetricsServer) error {
 
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
+	window, err := s.requestWindow(req.WindowSeconds)
+	if err != nil {
+		return err
+	}
 
-	for {
-		select {
+	interval, err := s.subscribeInterval(req)
+	if err != nil {
+		return err
+	}
 
-		case <-ticker.C:
-			// Send multiple metrics in the stream
-			metrics := []*pb.Metric{
-				s.makeMetric("events_per_window", float64(s.store.GetEventsPerWindow())),
-				s.makeMetric("total_throughput", s.store.GetTotalThroughput()),
-				s.makeMetric("total_error_rate", s.store.GetTotalErrorRate()),
+	threshold := req.ChangeThreshold
+	if threshold < 0 || math.IsNaN(threshold) {
+		return status.Error(codes.InvalidArgument, "change_threshold must not be negative")
+	}
+	onChange := req.OnChange || threshold > 0
+
+	names := []string{"events_per_window", "total_throughput", "total_error_rate"}
+	if len(req.MetricsNames) > 0 {
+		names = req.MetricsNames
+	}
+	// reject unknown names up front rather than on every tick
+	for _, name := range names {
+		if _, _, err := s.registry.lookup(name); err != nil {
+			return err
+		}
+	}
+
+	pushed := make(map[string]float64) // series -> value last pushed
+	push := func() error {
+		for _, name := range names {
+			metrics, err := s.resolve(name, window)
+			if err != nil {
+				return err
 			}
 
 			for _, metric := range metrics {
+				if onChange {
+					key := seriesKey(metric)
+					if last, ok := pushed[key]; ok && math.Abs(metric.Value-last) <= threshold {
+						continue
+					}
+					pushed[key] = metric.Value
+				}
+
 				if err := stream.Send(metric); err != nil {
 					log.Println("Client disconnected:", err)
 					return err
 				}
 			}
+		}
+		return nil
+	}
+
+	// push once right away so clients don't wait a full interval
+	if err := push(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+			if err := push(); err != nil {
+				return err
+			}
+
+		case <-s.shutdown:
+			return errShuttingDown
 
 		case <-stream.Context().Done():
 			log.Println("Client unsubscribed from stream")
@@ -102,6 +195,102 @@ func (s *MetricsServiceServer) SubscribeMetrics(req *pb.GetMetricsRequest, strea
 	}
 }
 
+// subscribeInterval returns the requested push interval clamped to the
+// server's bounds. Zero means the default interval.
+func (s *MetricsServiceServer) subscribeInterval(req *pb.GetMetricsRequest) (time.Duration, error) {
+	if req.IntervalMs < 0 {
+		return 0, status.Error(codes.InvalidArgument, "interval_ms must not be negative")
+	}
+
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval == 0 {
+		interval = DefaultSubscribeInterval
+	}
+	return min(max(interval, s.minInterval), s.maxInterval), nil
+}
+
+// seriesKey identifies a metric by its name and labels.
+func seriesKey(metric *pb.Metric) string {
+	if len(metric.Labels) == 0 {
+		return metric.Name
+	}
+
+	keys := make([]string, 0, len(metric.Labels))
+	for k := range metric.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(metric.Name)
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(metric.Labels[k])
+	}
+	return b.String()
+}
+
+// requestWindow validates the requested window. Zero means the server's
+// default window.
+func (s *MetricsServiceServer) requestWindow(windowSeconds int32) (time.Duration, error) {
+	window := time.Duration(windowSeconds) * time.Second
+	if window < 0 {
+		return 0, status.Error(codes.InvalidArgument, "window_seconds must not be negative")
+	}
+	if window > s.store.Retention() {
+		return 0, status.Errorf(codes.InvalidArgument,
+			"window_seconds must not exceed the retention of %d seconds", int(s.store.Retention().Seconds()))
+	}
+	return window, nil
+}
+
+// ListMetrics returns the catalog of metrics that can be requested by name.
+func (s *MetricsServiceServer) ListMetrics(ctx context.Context, req *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	resp := &pb.ListMetricsResponse{}
+	for _, def := range s.registry.List() {
+		resp.Metrics = append(resp.Metrics, &pb.MetricDescriptor{
+			Name:        def.Name,
+			Description: def.Description,
+			Unit:        def.Unit,
+			Labels:      def.Labels,
+			PerType:     def.PerType,
+			Windowed:    def.Windowed,
+		})
+	}
+	return resp, nil
+}
+
+// resolve computes the metrics a requested name refers to. Per-type metrics
+// are named kind:type (e.g. events:login); a type of * expands to one metric
+// per event type seen so far.
+func (s *MetricsServiceServer) resolve(name string, window time.Duration) ([]*pb.Metric, error) {
+	m, eventType, err := s.registry.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	types := []string{eventType}
+	if eventType == "*" {
+		types = s.store.GetEventTypes()
+	}
+
+	metrics := []*pb.Metric{}
+	for _, eventType := range types {
+		metricName := m.def.Name
+		if m.def.PerType {
+			metricName += ":" + eventType
+		}
+		for _, sample := range m.provider(Query{EventType: eventType, Window: window}) {
+			metric := s.makeMetric(metricName, sample.Value)
+			metric.Labels = sample.Labels
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics, nil
+}
+
 // Helper: create metric with timestamp
 func (s *MetricsServiceServer) makeMetric(name string, value float64) *pb.Metric {
 	return &pb.Metric{
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"sort"
+	"sync"
+)
+
+// OtherDimensionValue is the value that events are grouped under once a
+// dimension has reached its cardinality cap.
+const OtherDimensionValue = "__other__"
+
+// DefaultDimensionCardinality is the number of distinct values tracked per
+// dimension when no cap is configured.
+const DefaultDimensionCardinality = 100
+
+// DimensionStats is the breakdown of one event type for one dimension value.
+type DimensionStats struct {
+	Value  string
+	Count  int64
+	Values ValueStats
+}
+
+// dimension tracks counts and value aggregates per (event type, value) for
+// one metadata key.
+type dimension struct {
+	mu          sync.Mutex
+	cardinality int
+	admitted    map[string]struct{} // distinct values tracked so far
+	stats       map[dimensionKey]*dimensionStats
+}
+
+type dimensionKey struct {
+	eventType string
+	value     string
+}
+
+type dimensionStats struct {
+	count  int64
+	values valueAgg
+}
+
+func newDimension(cardinality int) *dimension {
+	if cardinality <= 0 {
+		cardinality = DefaultDimensionCardinality
+	}
+	return &dimension{
+		cardinality: cardinality,
+		admitted:    make(map[string]struct{}),
+		stats:       make(map[dimensionKey]*dimensionStats),
+	}
+}
+
+// statsFor returns the stats for an event type and value, folding the value
+// into OtherDimensionValue once the cap is reached. Callers hold d.mu.
+func (d *dimension) statsFor(eventType, value string) *dimensionStats {
+	if _, ok := d.admitted[value]; !ok && value != OtherDimensionValue {
+		if len(d.admitted) >= d.cardinality {
+			value = OtherDimensionValue
+		} else {
+			d.admitted[value] = struct{}{}
+		}
+	}
+
+	key := dimensionKey{eventType: eventType, value: value}
+	ds, ok := d.stats[key]
+	if !ok {
+		ds = &dimensionStats{}
+		d.stats[key] = ds
+	}
+	return ds
+}
+
+// RecordDimensionsN records n events with the same metadata and value against
+// every configured dimension whose key is present in metadata. The value is
+// aggregated only if hasValue is set.
+func (m *MetricStore) RecordDimensionsN(eventType string, metadata map[string]string, n int64, value float64, hasValue bool) {
+	eventType = m.typeKey(eventType)
+	for key, d := range m.dimensions {
+		dimValue, ok := metadata[key]
+		if !ok {
+			continue
+		}
+
+		d.mu.Lock()
+		ds := d.statsFor(eventType, dimValue)
+		ds.count += n
+		if hasValue {
+			ds.values.observe(value, n)
+		}
+		d.mu.Unlock()
+	}
+}
+
+// HasDimension reports whether key is a configured dimension
+func (m *MetricStore) HasDimension(key string) bool {
+	_, ok := m.dimensions[key]
+	return ok
+}
+
+// GetDimensionBreakdown returns the per-value breakdown of an event type for
+// a dimension, sorted by descending count.
+func (m *MetricStore) GetDimensionBreakdown(eventType, key string) []DimensionStats {
+	d, ok := m.dimensions[key]
+	if !ok {
+		return nil
+	}
+
+	d.mu.Lock()
+	out := []DimensionStats{}
+	for k, ds := range d.stats {
+		if k.eventType != eventType {
+			continue
+		}
+		out = append(out, DimensionStats{
+			Value:  k.value,
+			Count:  ds.count,
+			Values: ds.values.stats(),
+		})
+	}
+	d.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
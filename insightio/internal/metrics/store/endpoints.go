@@ -12,9 +12,6 @@ type EndpointStats struct {
 
 // GetTopSlowestEndpoints returns the k slowest endpoints sorted by average latency
 func (m *MetricStore) GetTopSlowestEndpoints(k int) []EndpointStats {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	type entry struct {
 		method string
 		avgMs  float64
@@ -23,14 +20,18 @@ func (m *MetricStore) GetTopSlowestEndpoints(k int) []EndpointStats {
 	}
 
 	list := []entry{}
-	for method, hist := range m.latencyMap {
+	m.rangeMethods(func(method string, ms *methodStats) {
+		hist := ms.latency(m.buckets)
+		if hist.total == 0 {
+			return
+		}
 		list = append(list, entry{
 			method: method,
 			avgMs:  hist.Avg(),
-			reqs:   m.reqCount[method],
-			errs:   m.errCount[method],
+			reqs:   ms.reqs(),
+			errs:   ms.errs(),
 		})
-	}
+	})
 
 	// sort by avg latency descending
 	sort.Slice(list, func(i, j int) bool {
@@ -53,3 +54,36 @@ func (m *MetricStore) GetTopSlowestEndpoints(k int) []EndpointStats {
 
 	return out
 }
+
+// MethodMetrics is a view of one method's all-time counters and latency histogram
+type MethodMetrics struct {
+	Method       string
+	Requests     int64
+	Errors       int64
+	BucketBounds []int64 // upper bounds in milliseconds; the last bucket also holds overflow
+	BucketCounts []int64 // per-bucket (non-cumulative) counts
+	LatencyCount int64
+	LatencySumMs int64
+}
+
+// GetAllMethodMetrics returns the metrics of every method seen so far, sorted by method
+func (m *MetricStore) GetAllMethodMetrics() []MethodMetrics {
+	out := []MethodMetrics{}
+	m.rangeMethods(func(method string, ms *methodStats) {
+		hist := ms.latency(m.buckets)
+		out = append(out, MethodMetrics{
+			Method:       method,
+			Requests:     ms.reqs(),
+			Errors:       ms.errs(),
+			BucketBounds: append([]int64(nil), hist.buckets...),
+			BucketCounts: hist.counts,
+			LatencyCount: hist.total,
+			LatencySumMs: hist.sumMs,
+		})
+	})
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
@@ -1,40 +1,74 @@
 package store
 
+import "time"
+
 // RecordError records an error for a specific method
 func (m *MetricStore) RecordError(method string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	ms := m.method(method)
+	s := ms.stripe()
+	s.errCount++
+	s.mu.Unlock()
+
+	ms.errors.add(time.Now(), 1)
+}
 
-	m.errCount[method]++
+// GetErrorCount returns the number of errors recorded for a specific method
+func (m *MetricStore) GetErrorCount(method string) int64 {
+	ms, ok := m.lookupMethod(method)
+	if !ok {
+		return 0
+	}
+	return ms.errs()
 }
 
 // GetErrorRate returns error rate as a percentage (0-100) for a specific method
 func (m *MetricStore) GetErrorRate(method string) float64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	ms, ok := m.lookupMethod(method)
+	if !ok {
+		return 0
+	}
 
-	reqs := m.reqCount[method]
+	reqs := ms.reqs()
 	if reqs == 0 {
 		return 0
 	}
 
-	errs := m.errCount[method]
+	errs := ms.errs()
 	return (float64(errs) / float64(reqs)) * 100.0
 }
 
 // GetTotalErrorRate returns overall error rate as a percentage across all methods
 func (m *MetricStore) GetTotalErrorRate() float64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	totalReqs := int64(0)
 	totalErrs := int64(0)
 
-	for method := range m.reqCount {
-		totalReqs += m.reqCount[method]
-		totalErrs += m.errCount[method]
+	m.rangeMethods(func(_ string, ms *methodStats) {
+		totalReqs += ms.reqs()
+		totalErrs += ms.errs()
+	})
+
+	if totalReqs == 0 {
+		return 0
 	}
 
+	return (float64(totalErrs) / float64(totalReqs)) * 100.0
+}
+
+// GetTotalErrorRateInWindow returns the overall error rate as a percentage
+// across all methods within the given window. A zero window uses the store's
+// sliding window.
+func (m *MetricStore) GetTotalErrorRateInWindow(window time.Duration) float64 {
+	window = m.windowOrDefault(window)
+	now := time.Now()
+
+	totalReqs := int64(0)
+	totalErrs := int64(0)
+
+	m.rangeMethods(func(_ string, ms *methodStats) {
+		totalReqs += ms.requests.sum(now, window)
+		totalErrs += ms.errors.sum(now, window)
+	})
+
 	if totalReqs == 0 {
 		return 0
 	}
@@ -1,55 +1,138 @@
 package store
 
-import "time"
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// eventTypeStats holds the all-time count and the windowed counts of one
+// event type.
+type eventTypeStats struct {
+	count atomic.Int64
+
+	mu     sync.Mutex
+	window *bucketRing
+}
+
+func (s *eventTypeStats) add(t time.Time, n int64) {
+	s.count.Add(n)
+	s.mu.Lock()
+	s.window.add(t, n)
+	s.mu.Unlock()
+}
+
+func (s *eventTypeStats) sum(now time.Time, window time.Duration) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.window.sum(now, window)
+}
 
 // AddEvent records an event in the store
 func (m *MetricStore) AddEvent(eventType string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	m.totalEvents++
-	now := time.Now()
-	m.eventTypeCounts[eventType]++
-	m.eventTimestamps = append(m.eventTimestamps, time.Now())
-
-	//clean up the timestamps  outside the window
-	threshold := now.Add(-m.windowSize)
-	validTimestamps := make([]time.Time, 0, len(m.eventTimestamps))
-	for _, ts := range m.eventTimestamps {
-		if ts.After(threshold) {
-			validTimestamps = append(validTimestamps, ts)
-		}
-	}
-	m.eventTimestamps = validTimestamps
+	m.AddEventAt(eventType, time.Now())
+}
+
+// AddEventAt records an event that occurred at t. Windowed counts bucket it
+// by t; times older than the retention only count towards the totals.
+func (m *MetricStore) AddEventAt(eventType string, t time.Time) {
+	m.AddEventsAt(eventType, 1, t)
+}
+
+// AddEventsAt records n events that occurred at t, e.g. one sampled event
+// standing in for the n it was picked from.
+func (m *MetricStore) AddEventsAt(eventType string, n int64, t time.Time) {
+	m.totalEvents.Add(n)
+	m.eventTypeStatsFor(m.typeKey(eventType)).add(t, n)
+	m.eventWindow.add(t, n)
 }
 
 // GetTotalEvents returns the total number of events recorded
 func (m *MetricStore) GetTotalEvents() int64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.totalEvents
+	return m.totalEvents.Load()
 }
 
 // GetEventTypeCount returns the count of events for a specific type
 func (m *MetricStore) GetEventTypeCount(eventType string) int64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.eventTypeCounts[eventType]
+	s, ok := m.eventTypeCounts.Load(eventType)
+	if !ok {
+		return 0
+	}
+	return s.(*eventTypeStats).count.Load()
+}
+
+// GetEventTypeCountInWindow returns the count of events for a specific type
+// within the given window. A zero window uses the store's sliding window.
+func (m *MetricStore) GetEventTypeCountInWindow(eventType string, window time.Duration) int64 {
+	s, ok := m.eventTypeCounts.Load(eventType)
+	if !ok {
+		return 0
+	}
+	return s.(*eventTypeStats).sum(time.Now(), m.windowOrDefault(window))
+}
+
+// GetEventTypeCounts returns the count of events for every type seen so far
+func (m *MetricStore) GetEventTypeCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	m.eventTypeCounts.Range(func(key, value any) bool {
+		counts[key.(string)] = value.(*eventTypeStats).count.Load()
+		return true
+	})
+	return counts
+}
+
+// GetEventTypes returns every event type seen so far, sorted.
+func (m *MetricStore) GetEventTypes() []string {
+	var types []string
+	m.eventTypeCounts.Range(func(key, value any) bool {
+		types = append(types, key.(string))
+		return true
+	})
+	sort.Strings(types)
+	return types
 }
 
 // GetEventsPerWindow returns the number of events within the sliding window
 func (m *MetricStore) GetEventsPerWindow() int64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.GetEventsInWindow(0)
+}
+
+// GetEventsInWindow returns the number of events within the given window.
+// A zero window uses the store's sliding window.
+func (m *MetricStore) GetEventsInWindow(window time.Duration) int64 {
+	return m.eventWindow.sum(time.Now(), m.windowOrDefault(window))
+}
+
+// typeKey returns the type that events of eventType are tracked under: the
+// type itself, or OtherEventType once the store tracks maxEventTypes types.
+// Every per-type structure is keyed by it, so the cap bounds them all.
+func (m *MetricStore) typeKey(eventType string) string {
+	if _, ok := m.eventTypeCounts.Load(eventType); ok {
+		return eventType
+	}
+
+	m.typesMu.Lock()
+	defer m.typesMu.Unlock()
 
-	now := time.Now()
-	threshold := now.Add(-m.windowSize)
+	if _, ok := m.eventTypeCounts.Load(eventType); ok || eventType == OtherEventType {
+		return eventType
+	}
+	if m.eventTypes >= m.maxEventTypes {
+		return OtherEventType
+	}
+	m.eventTypes++
+	m.eventTypeStatsFor(eventType)
+	return eventType
+}
 
-	count := int64(0)
-	for _, ts := range m.eventTimestamps {
-		if ts.After(threshold) {
-			count++
-		}
+// eventTypeStatsFor returns the stats for an event type, creating them on first use.
+func (m *MetricStore) eventTypeStatsFor(eventType string) *eventTypeStats {
+	if s, ok := m.eventTypeCounts.Load(eventType); ok {
+		return s.(*eventTypeStats)
 	}
-	return count
+	s, _ := m.eventTypeCounts.LoadOrStore(eventType, &eventTypeStats{
+		window: newBucketRing(m.eventSpan, m.resolution),
+	})
+	return s.(*eventTypeStats)
 }
@@ -0,0 +1,286 @@
+package store
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFunnelUsers is the number of users with a funnel in progress
+// tracked per funnel when no cap is configured.
+const DefaultFunnelUsers = 100000
+
+// FunnelDefinition declares a conversion funnel: a user converts by emitting
+// the Steps event types in order, all within Within of the first step.
+type FunnelDefinition struct {
+	Name   string
+	Steps  []string
+	Within time.Duration
+}
+
+func (d FunnelDefinition) validate() error {
+	if d.Name == "" {
+		return errors.New("funnel name is missing")
+	}
+	if len(d.Steps) < 2 {
+		return fmt.Errorf("funnel %q needs at least two steps", d.Name)
+	}
+	seen := make(map[string]bool, len(d.Steps))
+	for _, step := range d.Steps {
+		if step == "" {
+			return fmt.Errorf("funnel %q has an empty step", d.Name)
+		}
+		if seen[step] {
+			return fmt.Errorf("funnel %q has step %q more than once", d.Name, step)
+		}
+		seen[step] = true
+	}
+	if d.Within <= 0 {
+		return fmt.Errorf("funnel %q needs a positive time limit", d.Name)
+	}
+	return nil
+}
+
+// FunnelStep is the number of funnel attempts that reached one step.
+type FunnelStep struct {
+	EventType string
+	Count     int64
+}
+
+// FunnelReport holds the progress through a funnel of the attempts started
+// within a window.
+type FunnelReport struct {
+	Definition FunnelDefinition
+	Steps      []FunnelStep
+	Skipped    int64 // attempts not tracked because too many users were in progress
+}
+
+// funnelProgress is a user's attempt at a funnel: the last step reached and
+// when the first one was.
+type funnelProgress struct {
+	userID  string
+	step    int
+	started time.Time
+	index   int // position in the funnel's expiry heap
+}
+
+// progressHeap orders attempts by start, so those that can no longer
+// convert are found without scanning every user.
+type progressHeap []*funnelProgress
+
+func (h progressHeap) Len() int           { return len(h) }
+func (h progressHeap) Less(i, j int) bool { return h[i].started.Before(h[j].started) }
+
+func (h progressHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *progressHeap) Push(x any) {
+	p := x.(*funnelProgress)
+	p.index = len(*h)
+	*h = append(*h, p)
+}
+
+func (h *progressHeap) Pop() any {
+	old := *h
+	p := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return p
+}
+
+// funnel tracks per-user progress through one funnel. Steps reached are
+// counted at the time the attempt started, so a window holds a cohort of
+// attempts and conversion never exceeds 100%.
+type funnel struct {
+	def      FunnelDefinition
+	steps    map[string]int // event type -> step index
+	maxUsers int
+
+	mu      sync.Mutex
+	users   map[string]*funnelProgress
+	expiry  progressHeap // the attempts in users, oldest start first
+	reached []*bucketRing
+	totals  []int64
+	skipped int64
+}
+
+func newFunnel(def FunnelDefinition, maxUsers int, span, resolution time.Duration) *funnel {
+	if maxUsers <= 0 {
+		maxUsers = DefaultFunnelUsers
+	}
+	f := &funnel{
+		def:      def,
+		steps:    make(map[string]int, len(def.Steps)),
+		maxUsers: maxUsers,
+		users:    make(map[string]*funnelProgress),
+		reached:  make([]*bucketRing, len(def.Steps)),
+		totals:   make([]int64, len(def.Steps)),
+	}
+	for i, step := range def.Steps {
+		f.steps[step] = i
+		f.reached[i] = newBucketRing(span, resolution)
+	}
+	return f
+}
+
+// observe advances the user's attempt if eventType is its next step, or
+// starts one if it is the first step and none is in progress.
+func (f *funnel) observe(eventType, userID string, t time.Time) {
+	k, ok := f.steps[eventType]
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.users[userID]
+	if p != nil && t.Sub(p.started) > f.def.Within {
+		f.forget(p)
+		p = nil
+	}
+
+	switch {
+	case p == nil:
+		if k != 0 {
+			return
+		}
+		if len(f.users) >= f.maxUsers {
+			f.expire(t)
+			if len(f.users) >= f.maxUsers {
+				f.skipped++
+				return
+			}
+		}
+		f.track(userID, 0, t)
+		f.reach(0, t)
+
+	case k == p.step+1 && !t.Before(p.started):
+		p.step = k
+		f.reach(k, p.started)
+		if k == len(f.def.Steps)-1 {
+			f.forget(p)
+		}
+	}
+}
+
+func (f *funnel) reach(step int, started time.Time) {
+	f.totals[step]++
+	f.reached[step].add(started, 1)
+}
+
+// track starts following a user's attempt. Callers hold f.mu.
+func (f *funnel) track(userID string, step int, started time.Time) {
+	p := &funnelProgress{userID: userID, step: step, started: started}
+	f.users[userID] = p
+	heap.Push(&f.expiry, p)
+}
+
+// forget stops following an attempt. Callers hold f.mu.
+func (f *funnel) forget(p *funnelProgress) {
+	delete(f.users, p.userID)
+	heap.Remove(&f.expiry, p.index)
+}
+
+// expire forgets attempts that can no longer convert, oldest first.
+// Callers hold f.mu.
+func (f *funnel) expire(now time.Time) {
+	for len(f.expiry) > 0 && now.Sub(f.expiry[0].started) > f.def.Within {
+		f.forget(f.expiry[0])
+	}
+}
+
+func (f *funnel) report(now time.Time, window time.Duration) FunnelReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r := FunnelReport{
+		Definition: f.def,
+		Steps:      make([]FunnelStep, len(f.def.Steps)),
+		Skipped:    f.skipped,
+	}
+	for i, step := range f.def.Steps {
+		r.Steps[i] = FunnelStep{EventType: step, Count: f.reached[i].sum(now, window)}
+	}
+	return r
+}
+
+// RecordFunnelEventAt advances userID through every funnel that has
+// eventType as a step. Events must arrive in order per user to be matched.
+func (m *MetricStore) RecordFunnelEventAt(eventType, userID string, t time.Time) {
+	for _, f := range m.funnels {
+		f.observe(eventType, userID, t)
+	}
+}
+
+// GetFunnel returns the step counts of the attempts at a funnel started
+// within the window. A zero window uses the store's sliding window.
+func (m *MetricStore) GetFunnel(name string, window time.Duration) (FunnelReport, bool) {
+	f, ok := m.funnels[name]
+	if !ok {
+		return FunnelReport{}, false
+	}
+	return f.report(time.Now(), m.windowOrDefault(window)), true
+}
+
+// FunnelNames returns the names of the configured funnels, sorted.
+func (m *MetricStore) FunnelNames() []string {
+	names := make([]string, 0, len(m.funnels))
+	for name := range m.funnels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// funnelFile is the on-disk form of the funnel definitions.
+type funnelFile struct {
+	Funnels []struct {
+		Name          string   `json:"name"`
+		Steps         []string `json:"steps"`
+		WithinSeconds int      `json:"within_seconds"`
+	} `json:"funnels"`
+}
+
+// ReadFunnels reads the funnel definitions in path. A missing file yields
+// no funnels.
+func ReadFunnels(path string) ([]FunnelDefinition, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file funnelFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	defs := make([]FunnelDefinition, len(file.Funnels))
+	names := make(map[string]bool, len(file.Funnels))
+	for i, f := range file.Funnels {
+		defs[i] = FunnelDefinition{
+			Name:   f.Name,
+			Steps:  f.Steps,
+			Within: time.Duration(f.WithinSeconds) * time.Second,
+		}
+		if err := defs[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("%s: duplicate funnel %q", path, f.Name)
+		}
+		names[f.Name] = true
+	}
+	return defs, nil
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func newFunnelTestStore(maxUsers int) *MetricStore {
+	return NewMetricStoreWithOptions(Options{
+		Window:     time.Hour,
+		Resolution: time.Second,
+		Retention:  time.Hour,
+		Funnels: []FunnelDefinition{
+			{Name: "checkout", Steps: []string{"cart", "pay", "done"}, Within: 10 * time.Minute},
+		},
+		FunnelUsers: maxUsers,
+	})
+}
+
+// funnelCounts returns the step counts of the checkout funnel over the last hour.
+func funnelCounts(t *testing.T, m *MetricStore) ([]int64, int64) {
+	t.Helper()
+	report, ok := m.GetFunnel("checkout", time.Hour)
+	if !ok {
+		t.Fatal("funnel checkout not found")
+	}
+	counts := make([]int64, len(report.Steps))
+	for i, step := range report.Steps {
+		counts[i] = step.Count
+	}
+	return counts, report.Skipped
+}
+
+func TestFunnelProgress(t *testing.T) {
+	type event struct {
+		eventType string
+		at        time.Duration // after the start of the test
+	}
+	tests := []struct {
+		name   string
+		events []event
+		want   []int64
+	}{
+		{"converted", []event{{"cart", 0}, {"pay", time.Minute}, {"done", 2 * time.Minute}}, []int64{1, 1, 1}},
+		{"dropped off", []event{{"cart", 0}, {"pay", time.Minute}}, []int64{1, 1, 0}},
+		{"skipped a step", []event{{"cart", 0}, {"done", time.Minute}}, []int64{1, 0, 0}},
+		{"no first step", []event{{"pay", 0}, {"done", time.Minute}}, []int64{0, 0, 0}},
+		{"first step repeated", []event{{"cart", 0}, {"cart", time.Minute}, {"pay", 2 * time.Minute}}, []int64{1, 1, 0}},
+		{"converted again", []event{{"cart", 0}, {"pay", time.Minute}, {"done", 2 * time.Minute}, {"cart", 3 * time.Minute}}, []int64{2, 1, 1}},
+		{"too slow", []event{{"cart", 0}, {"pay", 11 * time.Minute}}, []int64{1, 0, 0}},
+		{"restarted after expiry", []event{{"cart", 0}, {"cart", 11 * time.Minute}, {"pay", 12 * time.Minute}}, []int64{2, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFunnelTestStore(0)
+			start := time.Now().Add(-30 * time.Minute)
+			for _, e := range tt.events {
+				m.RecordFunnelEventAt(e.eventType, "u1", start.Add(e.at))
+			}
+			if got, _ := funnelCounts(t, m); !slices.Equal(got, tt.want) {
+				t.Errorf("step counts %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunnelExpiryFreesRoom(t *testing.T) {
+	m := newFunnelTestStore(2)
+	start := time.Now().Add(-30 * time.Minute)
+
+	m.RecordFunnelEventAt("cart", "a", start)
+	m.RecordFunnelEventAt("cart", "b", start.Add(time.Minute))
+	// both attempts can still convert, so there is no room for c
+	m.RecordFunnelEventAt("cart", "c", start.Add(2*time.Minute))
+	if _, skipped := funnelCounts(t, m); skipped != 1 {
+		t.Fatalf("skipped %d attempts, want 1", skipped)
+	}
+
+	// a's attempt has expired by now and makes room for d
+	m.RecordFunnelEventAt("cart", "d", start.Add(10*time.Minute+time.Second))
+	m.RecordFunnelEventAt("pay", "d", start.Add(11*time.Minute))
+	// b's attempt is still tracked
+	m.RecordFunnelEventAt("pay", "b", start.Add(10*time.Minute+2*time.Second))
+	// a's is not
+	m.RecordFunnelEventAt("pay", "a", start.Add(10*time.Minute+3*time.Second))
+
+	got, skipped := funnelCounts(t, m)
+	if want := []int64{3, 2, 0}; !slices.Equal(got, want) {
+		t.Errorf("step counts %v, want %v", got, want)
+	}
+	if skipped != 1 {
+		t.Errorf("skipped %d attempts, want 1", skipped)
+	}
+}
+
+func TestFunnelCountsByAttemptStart(t *testing.T) {
+	m := newFunnelTestStore(0)
+	now := time.Now()
+
+	// started 12 minutes ago and converted 5 minutes ago: the conversion
+	// belongs to the attempt's start, outside the last 10 minutes
+	m.RecordFunnelEventAt("cart", "u1", now.Add(-12*time.Minute))
+	m.RecordFunnelEventAt("pay", "u1", now.Add(-5*time.Minute))
+
+	report, _ := m.GetFunnel("checkout", 10*time.Minute)
+	for _, step := range report.Steps {
+		if step.Count != 0 {
+			t.Errorf("step %s counts %d in the last 10 minutes, want 0", step.EventType, step.Count)
+		}
+	}
+	if got, _ := funnelCounts(t, m); !slices.Equal(got, []int64{1, 1, 0}) {
+		t.Errorf("step counts over the hour %v, want [1 1 0]", got)
+	}
+}
+
+func TestFunnelDefinitionValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		def  FunnelDefinition
+	}{
+		{"no name", FunnelDefinition{Steps: []string{"a", "b"}, Within: time.Minute}},
+		{"one step", FunnelDefinition{Name: "f", Steps: []string{"a"}, Within: time.Minute}},
+		{"empty step", FunnelDefinition{Name: "f", Steps: []string{"a", ""}, Within: time.Minute}},
+		{"repeated step", FunnelDefinition{Name: "f", Steps: []string{"a", "a"}, Within: time.Minute}},
+		{"no time limit", FunnelDefinition{Name: "f", Steps: []string{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		if err := tt.def.validate(); err == nil {
+			t.Errorf("%s: validate accepted %+v", tt.name, tt.def)
+		}
+	}
+}
@@ -47,6 +47,25 @@ func (h *LatencyHist) Observe(d time.Duration) {
 	h.counts[len(h.counts)-1]++
 }
 
+// merge adds the observations of other into h. Both must share bucket bounds.
+func (h *LatencyHist) merge(other *LatencyHist) {
+	if other.total == 0 {
+		return
+	}
+
+	h.total += other.total
+	h.sumMs += other.sumMs
+	if h.minMs == -1 || other.minMs < h.minMs {
+		h.minMs = other.minMs
+	}
+	if other.maxMs > h.maxMs {
+		h.maxMs = other.maxMs
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+}
+
 // Avg returns the average latency in milliseconds
 func (h *LatencyHist) Avg() float64 {
 	if h.total == 0 {
@@ -94,6 +113,11 @@ func (h *LatencyHist) GetDistribution() map[int64]int64 {
 	return dist
 }
 
+// Count returns the number of observations
+func (h *LatencyHist) Count() int64 {
+	return h.total
+}
+
 // GetMin returns minimum latency in milliseconds
 func (h *LatencyHist) GetMin() float64 {
 	if h.minMs == -1 {
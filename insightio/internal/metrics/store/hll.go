@@ -0,0 +1,226 @@
+package store
+
+import (
+	"cmp"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// DefaultSketchResolution is the width of a unique-user sketch bucket.
+// Sketches are much larger than counters, so they use coarser buckets.
+const DefaultSketchResolution = 10 * time.Second
+
+const (
+	hllPrecision = 12 // 4096 registers, ~1.6% standard error
+	hllRegisters = 1 << hllPrecision
+)
+
+// hllSparseMax is the number of set registers a sketch holds sparsely
+// before switching to the dense array. Sparse entries take four bytes, so
+// the sparse form stays well under the 4 KB of the dense one.
+const hllSparseMax = hllRegisters / 8
+
+// hyperLogLog estimates the number of distinct values added to it.
+// Sketches merge by taking the register-wise maximum, so per-bucket sketches
+// can be combined into any window.
+//
+// The register array is allocated lazily: a sketch starts sparse, holding
+// only the registers that are set, which keeps the many buckets that see
+// few users small.
+type hyperLogLog struct {
+	registers []uint8  // dense registers, nil while sparse
+	sparse    []uint32 // index<<8 | value of each set register, sorted by index
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{}
+}
+
+// add records a 64-bit hash of a value.
+func (h *hyperLogLog) add(hash uint64) {
+	idx := uint32(hash >> (64 - hllPrecision))
+	// remaining bits, with a sentinel so rho is bounded
+	rest := hash<<hllPrecision | 1<<(hllPrecision-1)
+	h.set(idx, uint8(bits.LeadingZeros64(rest)+1))
+}
+
+// set raises register idx to rho.
+func (h *hyperLogLog) set(idx uint32, rho uint8) {
+	if h.registers != nil {
+		if rho > h.registers[idx] {
+			h.registers[idx] = rho
+		}
+		return
+	}
+
+	i, found := slices.BinarySearchFunc(h.sparse, idx, func(e, idx uint32) int {
+		return cmp.Compare(e>>8, idx)
+	})
+	switch {
+	case found:
+		if rho > uint8(h.sparse[i]) {
+			h.sparse[i] = idx<<8 | uint32(rho)
+		}
+	case len(h.sparse) < hllSparseMax:
+		h.sparse = slices.Insert(h.sparse, i, idx<<8|uint32(rho))
+	default:
+		h.registers = h.dense()
+		h.sparse = nil
+		h.registers[idx] = rho
+	}
+}
+
+// dense returns the registers as a full array, copied.
+func (h *hyperLogLog) dense() []uint8 {
+	out := make([]uint8, hllRegisters)
+	if h.registers != nil {
+		copy(out, h.registers)
+		return out
+	}
+	for _, e := range h.sparse {
+		out[e>>8] = uint8(e)
+	}
+	return out
+}
+
+func (h *hyperLogLog) merge(other *hyperLogLog) {
+	if other.registers == nil {
+		for _, e := range other.sparse {
+			h.set(e>>8, uint8(e))
+		}
+		return
+	}
+
+	if h.registers == nil {
+		h.registers = h.dense()
+		h.sparse = nil
+	}
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// reset empties the sketch, releasing the dense registers.
+func (h *hyperLogLog) reset() {
+	h.registers = nil
+	h.sparse = nil
+}
+
+// estimate returns the estimated cardinality.
+func (h *hyperLogLog) estimate() float64 {
+	m := float64(hllRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	if h.registers != nil {
+		for _, r := range h.registers {
+			sum += math.Ldexp(1, -int(r))
+			if r == 0 {
+				zeros++
+			}
+		}
+	} else {
+		// every register not held sparsely is zero
+		zeros = hllRegisters - len(h.sparse)
+		sum = float64(zeros)
+		for _, e := range h.sparse {
+			sum += math.Ldexp(1, -int(uint8(e)))
+		}
+	}
+
+	est := alpha * m * m / sum
+	// small range correction: linear counting is more accurate here
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+	return est
+}
+
+// hashUser hashes a user id for the sketches. The hash is deterministic so
+// sketches restored from a snapshot keep matching new observations.
+func hashUser(userID string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(userID))
+	x := f.Sum64()
+
+	// splitmix64 finalizer to spread FNV's weak high bits
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// sketchRing keeps a HyperLogLog per time bucket. Sketches are allocated
+// lazily, so idle buckets cost nothing and quiet ones little.
+type sketchRing struct {
+	resolution time.Duration
+	buckets    []sketchBucket
+}
+
+type sketchBucket struct {
+	epoch  int64
+	sketch *hyperLogLog
+}
+
+func newSketchRing(span, resolution time.Duration) *sketchRing {
+	counts := newBucketRing(span, resolution)
+	return &sketchRing{
+		resolution: counts.resolution,
+		buckets:    make([]sketchBucket, len(counts.buckets)),
+	}
+}
+
+func (r *sketchRing) epochOf(t time.Time) int64 {
+	return t.UnixNano() / int64(r.resolution)
+}
+
+// bucket returns the sketch for the bucket holding t, recycling a stale
+// slot, or nil if t is older than anything the ring can hold.
+func (r *sketchRing) bucket(t time.Time) *hyperLogLog {
+	epoch := r.epochOf(t)
+	b := &r.buckets[ringIndex(epoch, len(r.buckets))]
+	if b.sketch == nil {
+		b.epoch = epoch
+		b.sketch = newHyperLogLog()
+	} else if b.epoch != epoch {
+		if b.epoch > epoch {
+			return nil
+		}
+		b.epoch = epoch
+		b.sketch.reset()
+	}
+	return b.sketch
+}
+
+func (r *sketchRing) add(t time.Time, hash uint64) {
+	if sketch := r.bucket(t); sketch != nil {
+		sketch.add(hash)
+	}
+}
+
+// estimate merges the sketches in the window ending at now and returns the
+// estimated number of distinct users.
+func (r *sketchRing) estimate(now time.Time, window time.Duration) int64 {
+	newest := r.epochOf(now)
+
+	merged := newHyperLogLog()
+	empty := true
+	for epoch := newest - windowBuckets(window, r.resolution, len(r.buckets)) + 1; epoch <= newest; epoch++ {
+		if b := &r.buckets[ringIndex(epoch, len(r.buckets))]; b.sketch != nil && b.epoch == epoch {
+			merged.merge(b.sketch)
+			empty = false
+		}
+	}
+	if empty {
+		return 0
+	}
+	return int64(math.Round(merged.estimate()))
+}
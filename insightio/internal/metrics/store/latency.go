@@ -4,24 +4,30 @@ import "time"
 
 // RecordLatency records latency for a specific method
 func (m *MetricStore) RecordLatency(method string, d time.Duration) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	s := m.method(method).stripe()
+	defer s.mu.Unlock()
 
-	hist, ok := m.latencyMap[method]
+	s.latency.Observe(d)
+}
+
+// latencyHist returns a merged histogram for a method, or false if the
+// method has no latency observations.
+func (m *MetricStore) latencyHist(method string) (*LatencyHist, bool) {
+	ms, ok := m.lookupMethod(method)
 	if !ok {
-		hist = NewLatencyHist(m.buckets)
-		m.latencyMap[method] = hist
+		return nil, false
 	}
 
-	hist.Observe(d)
+	hist := ms.latency(m.buckets)
+	if hist.total == 0 {
+		return nil, false
+	}
+	return hist, true
 }
 
 // GetLatencyPercentile returns the latency percentile for a specific method
 func (m *MetricStore) GetLatencyPercentile(method string, percentile float64) float64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	hist, ok := m.latencyMap[method]
+	hist, ok := m.latencyHist(method)
 	if !ok {
 		return 0
 	}
@@ -31,10 +37,7 @@ func (m *MetricStore) GetLatencyPercentile(method string, percentile float64) fl
 
 // GetLatencyDistribution returns the latency distribution for a specific method
 func (m *MetricStore) GetLatencyDistribution(method string) map[int64]int64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	hist, ok := m.latencyMap[method]
+	hist, ok := m.latencyHist(method)
 	if !ok {
 		return make(map[int64]int64)
 	}
@@ -55,10 +58,7 @@ type LatencyStats struct {
 
 // GetLatencyStats returns comprehensive latency stats for a method
 func (m *MetricStore) GetLatencyStats(method string) LatencyStats {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	hist, ok := m.latencyMap[method]
+	hist, ok := m.latencyHist(method)
 	if !ok {
 		return LatencyStats{}
 	}
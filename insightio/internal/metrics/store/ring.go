@@ -0,0 +1,97 @@
+package store
+
+import "time"
+
+// DefaultResolution is the width of a window bucket.
+const DefaultResolution = time.Second
+
+// bucketRing counts occurrences in fixed-width time buckets covering a
+// bounded span. Buckets are reused as time moves forward, so memory stays
+// constant and reads are O(buckets) regardless of traffic.
+// It is not safe for concurrent use; the mutex of the struct holding it
+// (e.g. syncRing, eventTypeStats, funnel) guards it.
+type bucketRing struct {
+	resolution time.Duration
+	buckets    []ringBucket
+}
+
+type ringBucket struct {
+	epoch int64 // bucket start in units of resolution since the Unix epoch
+	count int64
+}
+
+// newBucketRing creates a ring able to answer windows up to span.
+func newBucketRing(span, resolution time.Duration) *bucketRing {
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+	n := int((span + resolution - 1) / resolution)
+	if n < 1 {
+		n = 1
+	}
+	return &bucketRing{
+		resolution: resolution,
+		buckets:    make([]ringBucket, n),
+	}
+}
+
+func (r *bucketRing) epochOf(t time.Time) int64 {
+	return t.UnixNano() / int64(r.resolution)
+}
+
+func (r *bucketRing) slot(epoch int64) *ringBucket {
+	return &r.buckets[ringIndex(epoch, len(r.buckets))]
+}
+
+// ringIndex returns the slot of a bucket in a ring of n slots. Epochs before
+// 1970 are negative, so the remainder is brought back into range.
+func ringIndex(epoch int64, n int) int {
+	i := epoch % int64(n)
+	if i < 0 {
+		i += int64(n)
+	}
+	return int(i)
+}
+
+// add records n occurrences at time t.
+func (r *bucketRing) add(t time.Time, n int64) {
+	epoch := r.epochOf(t)
+	b := r.slot(epoch)
+	if b.epoch != epoch {
+		// slot still holds an older bucket; recycle it
+		if b.epoch > epoch {
+			return // t is older than anything the ring can hold
+		}
+		b.epoch = epoch
+		b.count = 0
+	}
+	b.count += n
+}
+
+// sum returns the number of occurrences in the window ending at now.
+// The window is rounded up to whole buckets and capped at the ring span.
+// Only the buckets in the window are visited.
+func (r *bucketRing) sum(now time.Time, window time.Duration) int64 {
+	newest := r.epochOf(now)
+
+	total := int64(0)
+	for epoch := newest - windowBuckets(window, r.resolution, len(r.buckets)) + 1; epoch <= newest; epoch++ {
+		if b := r.slot(epoch); b.epoch == epoch {
+			total += b.count
+		}
+	}
+	return total
+}
+
+// windowBuckets converts a window to a bucket count within a ring of n
+// buckets of the given resolution.
+func windowBuckets(window, resolution time.Duration, n int) int64 {
+	buckets := int64((window + resolution - 1) / resolution)
+	if buckets < 1 {
+		buckets = 1
+	}
+	if buckets > int64(n) {
+		buckets = int64(n)
+	}
+	return buckets
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// DefaultSessionUsers is the number of users with an open session tracked
+// when no cap is configured.
+const DefaultSessionUsers = 100000
+
+// SessionStats summarizes user sessions. Durations, events per session and
+// the bounce rate cover sessions that have ended.
+type SessionStats struct {
+	Active      int64   // sessions still open
+	Started     int64   // sessions started
+	Ended       int64   // sessions closed after the inactivity gap
+	AvgDuration float64 // seconds from the first event to the last
+	AvgEvents   float64 // events per session
+	BounceRate  float64 // percentage 0-100 of sessions with a single event
+}
+
+// session is the open session of one user.
+type session struct {
+	userID string
+	start  time.Time
+	last   time.Time
+	events int64
+	index  int // position in the tracker's expiry heap
+}
+
+// sessionHeap orders open sessions by their last event, so the idle ones
+// are found without scanning every session.
+type sessionHeap []*session
+
+func (h sessionHeap) Len() int           { return len(h) }
+func (h sessionHeap) Less(i, j int) bool { return h[i].last.Before(h[j].last) }
+
+func (h sessionHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *sessionHeap) Push(x any) {
+	sess := x.(*session)
+	sess.index = len(*h)
+	*h = append(*h, sess)
+}
+
+func (h *sessionHeap) Pop() any {
+	old := *h
+	sess := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return sess
+}
+
+// sessionTracker groups each user's events into sessions: an event less
+// than gap after the user's previous one continues the session, a later one
+// starts a new session. Sessions are counted when they start and summarized
+// when they end, bucketed by those times.
+//
+// Sessions end by event time: once an event gap later than a session's last
+// one has been seen from any user. Replay thus ends the same sessions, and
+// while no events arrive idle sessions stay open.
+type sessionTracker struct {
+	gap      time.Duration
+	maxUsers int
+
+	mu        sync.Mutex
+	open      map[string]*session
+	expiry    sessionHeap // the sessions in open, least recently active first
+	watermark time.Time   // latest event time seen
+	untracked int64       // events of users beyond maxUsers
+
+	started       int64
+	startedWindow *bucketRing
+	durations     valueAgg // seconds, per ended session
+	durWindow     *valueRing
+	events        valueAgg // events, per ended session
+	eventsWindow  *valueRing
+	bounces       int64
+	bounceWindow  *bucketRing
+}
+
+func newSessionTracker(gap time.Duration, maxUsers int, span, resolution time.Duration) *sessionTracker {
+	if maxUsers <= 0 {
+		maxUsers = DefaultSessionUsers
+	}
+	return &sessionTracker{
+		gap:           gap,
+		maxUsers:      maxUsers,
+		open:          make(map[string]*session),
+		startedWindow: newBucketRing(span, resolution),
+		durWindow:     newValueRing(span, resolution),
+		eventsWindow:  newValueRing(span, resolution),
+		bounceWindow:  newBucketRing(span, resolution),
+	}
+}
+
+// observe adds an event of userID at t to the user's session.
+func (s *sessionTracker) observe(userID string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.After(s.watermark) {
+		s.watermark = t
+		s.expire()
+	}
+
+	sess := s.open[userID]
+	if sess != nil && t.Sub(sess.last) >= s.gap {
+		s.close(sess)
+		sess = nil
+	}
+
+	if sess == nil {
+		if len(s.open) >= s.maxUsers {
+			s.untracked++
+			return
+		}
+		s.track(&session{userID: userID, start: t, last: t, events: 1})
+		s.started++
+		s.startedWindow.add(t, 1)
+		return
+	}
+
+	// late events count towards the session without moving its start
+	if t.After(sess.last) {
+		sess.last = t
+		heap.Fix(&s.expiry, sess.index)
+	}
+	sess.events++
+}
+
+// expire ends the sessions idle for at least the gap before the watermark,
+// least recently active first. Callers hold s.mu.
+func (s *sessionTracker) expire() {
+	for len(s.expiry) > 0 && s.watermark.Sub(s.expiry[0].last) >= s.gap {
+		s.close(s.expiry[0])
+	}
+}
+
+// track opens a session. Callers hold s.mu.
+func (s *sessionTracker) track(sess *session) {
+	s.open[sess.userID] = sess
+	heap.Push(&s.expiry, sess)
+}
+
+// close ends an open session. Callers hold s.mu.
+func (s *sessionTracker) close(sess *session) {
+	s.end(sess)
+	delete(s.open, sess.userID)
+	heap.Remove(&s.expiry, sess.index)
+}
+
+// end records a session that ended, bucketed by its last event.
+func (s *sessionTracker) end(sess *session) {
+	duration := sess.last.Sub(sess.start).Seconds()
+	events := float64(sess.events)
+
+	s.durations.observe(duration, 1)
+	s.durWindow.observe(sess.last, duration, 1)
+	s.events.observe(events, 1)
+	s.eventsWindow.observe(sess.last, events, 1)
+	if sess.events == 1 {
+		s.bounces++
+		s.bounceWindow.add(sess.last, 1)
+	}
+}
+
+// stats summarizes sessions started and ended within window, or since the
+// server started if window is zero.
+func (s *sessionTracker) stats(now time.Time, window time.Duration) SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	started, durations, events, bounces := s.started, s.durations, s.events, s.bounces
+	if window > 0 {
+		started = s.startedWindow.sum(now, window)
+		durations = s.durWindow.aggregate(now, window)
+		events = s.eventsWindow.aggregate(now, window)
+		bounces = s.bounceWindow.sum(now, window)
+	}
+
+	out := SessionStats{
+		Active:      int64(len(s.open)),
+		Started:     started,
+		Ended:       durations.count,
+		AvgDuration: durations.stats().Avg,
+		AvgEvents:   events.stats().Avg,
+	}
+	if durations.count > 0 {
+		out.BounceRate = float64(bounces) / float64(durations.count) * 100
+	}
+	return out
+}
+
+// TracksSessions reports whether the store groups events into sessions.
+func (m *MetricStore) TracksSessions() bool {
+	return m.sessions != nil
+}
+
+// RecordSessionEventAt adds an event of userID that occurred at t to the
+// user's session.
+func (m *MetricStore) RecordSessionEventAt(userID string, t time.Time) {
+	if m.sessions != nil {
+		m.sessions.observe(userID, t)
+	}
+}
+
+// GetSessionStats returns session statistics since the server started.
+func (m *MetricStore) GetSessionStats() SessionStats {
+	if m.sessions == nil {
+		return SessionStats{}
+	}
+	return m.sessions.stats(time.Now(), 0)
+}
+
+// GetSessionStatsInWindow returns statistics of the sessions started and
+// ended within the window. A zero window uses the store's sliding window.
+func (m *MetricStore) GetSessionStatsInWindow(window time.Duration) SessionStats {
+	if m.sessions == nil {
+		return SessionStats{}
+	}
+	return m.sessions.stats(time.Now(), m.windowOrDefault(window))
+}
+
+// UntrackedSessionEvents returns the number of events left out of sessions
+// because too many users had a session open.
+func (m *MetricStore) UntrackedSessionEvents() int64 {
+	if m.sessions == nil {
+		return 0
+	}
+	m.sessions.mu.Lock()
+	defer m.sessions.mu.Unlock()
+	return m.sessions.untracked
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+const sessionTestGap = 30 * time.Minute
+
+func newSessionTestStore(maxUsers int) *MetricStore {
+	return NewMetricStoreWithOptions(Options{
+		Window:       time.Hour,
+		Resolution:   time.Second,
+		Retention:    3 * time.Hour,
+		SessionGap:   sessionTestGap,
+		SessionUsers: maxUsers,
+	})
+}
+
+func TestSessions(t *testing.T) {
+	type event struct {
+		user string
+		at   time.Duration // after the start of the test
+	}
+	tests := []struct {
+		name   string
+		events []event
+		want   SessionStats
+	}{
+		{
+			name:   "one open session",
+			events: []event{{"a", 0}, {"a", 10 * time.Minute}},
+			want:   SessionStats{Active: 1, Started: 1},
+		},
+		{
+			name:   "gap starts a new session",
+			events: []event{{"a", 0}, {"a", 10 * time.Minute}, {"a", 40 * time.Minute}},
+			want:   SessionStats{Active: 1, Started: 2, Ended: 1, AvgDuration: 600, AvgEvents: 2},
+		},
+		{
+			name:   "another user's event ends an idle session",
+			events: []event{{"a", 0}, {"b", 30 * time.Minute}},
+			want:   SessionStats{Active: 1, Started: 2, Ended: 1, AvgEvents: 1, BounceRate: 100},
+		},
+		{
+			name:   "idle sessions wait for the watermark",
+			events: []event{{"a", 0}, {"b", 10 * time.Minute}},
+			want:   SessionStats{Active: 2, Started: 2},
+		},
+		{
+			name:   "late event joins its session",
+			events: []event{{"a", 10 * time.Minute}, {"b", 20 * time.Minute}, {"a", 0}},
+			want:   SessionStats{Active: 2, Started: 2},
+		},
+		{
+			// c's late session is already idle, and ends as soon as the
+			// watermark moves on
+			name: "late session ends once the watermark advances",
+			events: []event{
+				{"a", 0}, {"b", 35 * time.Minute}, {"c", 5 * time.Minute},
+				{"a", 36 * time.Minute},
+			},
+			want: SessionStats{Active: 2, Started: 4, Ended: 2, AvgEvents: 1, BounceRate: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newSessionTestStore(0)
+			start := time.Now().Add(-2 * time.Hour)
+			for _, e := range tt.events {
+				m.RecordSessionEventAt(e.user, start.Add(e.at))
+			}
+			if got := m.GetSessionStats(); got != tt.want {
+				t.Errorf("stats %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionsUntracked(t *testing.T) {
+	m := newSessionTestStore(2)
+	start := time.Now().Add(-time.Hour)
+
+	for _, user := range []string{"a", "b", "c"} {
+		m.RecordSessionEventAt(user, start)
+	}
+	if got := m.UntrackedSessionEvents(); got != 1 {
+		t.Fatalf("untracked %d events, want 1", got)
+	}
+
+	// ending the open sessions makes room again
+	m.RecordSessionEventAt("c", start.Add(sessionTestGap))
+	if got := m.UntrackedSessionEvents(); got != 1 {
+		t.Errorf("untracked %d events, want 1", got)
+	}
+	if got := m.GetSessionStats(); got.Active != 1 || got.Ended != 2 {
+		t.Errorf("stats %+v, want 1 active and 2 ended", got)
+	}
+}
+
+func TestSessionsWindow(t *testing.T) {
+	m := newSessionTestStore(0)
+	now := time.Now()
+
+	// a session that ended two hours ago, and one that ended recently
+	m.RecordSessionEventAt("a", now.Add(-150*time.Minute))
+	m.RecordSessionEventAt("b", now.Add(-45*time.Minute))
+	m.RecordSessionEventAt("b", now.Add(-44*time.Minute))
+	m.RecordSessionEventAt("c", now.Add(-5*time.Minute))
+
+	got := m.GetSessionStatsInWindow(time.Hour)
+	want := SessionStats{Active: 1, Started: 2, Ended: 1, AvgDuration: 60, AvgEvents: 2}
+	if got != want {
+		t.Errorf("stats in the last hour %+v, want %+v", got, want)
+	}
+	if all := m.GetSessionStats(); all.Started != 3 || all.Ended != 2 {
+		t.Errorf("stats since start %+v, want 3 started and 2 ended", all)
+	}
+}
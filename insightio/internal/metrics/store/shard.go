@@ -0,0 +1,134 @@
+package store
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// cacheLinePad keeps neighbouring stripes off the same cache line.
+type cacheLinePad [64]byte
+
+// stripeCount returns the number of stripes to use per striped value:
+// GOMAXPROCS rounded up to a power of two.
+func stripeCount() int {
+	n := 1
+	for n < runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+	return n
+}
+
+// pickStripe returns a random stripe index. Writers spread across stripes so
+// concurrent writers rarely share a lock; readers merge all stripes.
+func pickStripe(n int) int {
+	return int(rand.Uint32() & uint32(n-1))
+}
+
+// methodStats holds the request, error, latency and window state of one
+// method. Counters and latency are striped across several locks; the
+// windows are not, see syncRing.
+type methodStats struct {
+	stripes  []methodStripe
+	requests *syncRing // requests per bucket
+	errors   *syncRing // errors per bucket
+}
+
+type methodStripe struct {
+	mu       sync.Mutex
+	reqCount int64
+	errCount int64
+	latency  *LatencyHist
+	_        cacheLinePad
+}
+
+func newMethodStats(stripes int, buckets []int64, span, resolution time.Duration) *methodStats {
+	ms := &methodStats{
+		stripes:  make([]methodStripe, stripes),
+		requests: newSyncRing(span, resolution),
+		errors:   newSyncRing(span, resolution),
+	}
+	for i := range ms.stripes {
+		ms.stripes[i].latency = NewLatencyHist(buckets)
+	}
+	return ms
+}
+
+// stripe returns a random stripe, locked.
+func (ms *methodStats) stripe() *methodStripe {
+	s := &ms.stripes[pickStripe(len(ms.stripes))]
+	s.mu.Lock()
+	return s
+}
+
+func (ms *methodStats) reqs() int64 {
+	total := int64(0)
+	for i := range ms.stripes {
+		s := &ms.stripes[i]
+		s.mu.Lock()
+		total += s.reqCount
+		s.mu.Unlock()
+	}
+	return total
+}
+
+func (ms *methodStats) errs() int64 {
+	total := int64(0)
+	for i := range ms.stripes {
+		s := &ms.stripes[i]
+		s.mu.Lock()
+		total += s.errCount
+		s.mu.Unlock()
+	}
+	return total
+}
+
+// latency returns a merged copy of the stripe histograms.
+func (ms *methodStats) latency(buckets []int64) *LatencyHist {
+	merged := NewLatencyHist(buckets)
+	for i := range ms.stripes {
+		s := &ms.stripes[i]
+		s.mu.Lock()
+		merged.merge(s.latency)
+		s.mu.Unlock()
+	}
+	return merged
+}
+
+// syncRing is a bucketRing behind its own lock. Rings span the whole
+// retention, so they are not striped: a copy per stripe would multiply the
+// largest allocations in the store by the stripe count, and every read
+// would visit each copy.
+type syncRing struct {
+	mu   sync.Mutex
+	ring *bucketRing
+}
+
+func newSyncRing(span, resolution time.Duration) *syncRing {
+	return &syncRing{ring: newBucketRing(span, resolution)}
+}
+
+func (r *syncRing) add(t time.Time, n int64) {
+	r.mu.Lock()
+	r.ring.add(t, n)
+	r.mu.Unlock()
+}
+
+func (r *syncRing) sum(now time.Time, window time.Duration) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ring.sum(now, window)
+}
+
+func (r *syncRing) snapshot() *WindowSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ring.snapshot()
+}
+
+func (r *syncRing) restore(ws *WindowSnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ring.restore(ws)
+}
@@ -0,0 +1,624 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// SnapshotVersion is the format version written by WriteSnapshot.
+//
+// Version history:
+//
+//	1: windows stored as raw event/request timestamps
+//	2: windows stored as time buckets
+const SnapshotVersion = 2
+
+// Snapshot is a point-in-time copy of the MetricStore that can be written to
+// disk and restored after a restart.
+type Snapshot struct {
+	Version          int                        `json:"version"`
+	TakenAt          time.Time                  `json:"taken_at"`
+	WALSeq           uint64                     `json:"wal_seq"` // last WAL record reflected in the snapshot
+	TotalEvents      int64                      `json:"total_events"`
+	EventTypeCounts  map[string]int64           `json:"event_type_counts"`
+	EventWindow      *WindowSnapshot            `json:"event_window,omitempty"`
+	EventTypeWindows map[string]*WindowSnapshot `json:"event_type_windows,omitempty"`
+	EventValues      map[string]ValueSnapshot   `json:"event_values,omitempty"`
+	Methods          map[string]MethodSnapshot  `json:"methods"`
+
+	UniqueUsers       []SketchSnapshot            `json:"unique_users,omitempty"`
+	UniqueUsersByType map[string][]SketchSnapshot `json:"unique_users_by_type,omitempty"`
+
+	Dimensions map[string][]DimensionSnapshot `json:"dimensions,omitempty"`
+	Funnels    map[string]FunnelSnapshot      `json:"funnels,omitempty"`
+	Sessions   *SessionSnapshot               `json:"sessions,omitempty"`
+}
+
+// SessionSnapshot holds the open sessions and the session aggregates.
+type SessionSnapshot struct {
+	Open          map[string]SessionState `json:"open,omitempty"`
+	Watermark     time.Time               `json:"watermark"`
+	Untracked     int64                   `json:"untracked,omitempty"`
+	Started       int64                   `json:"started"`
+	StartedWindow *WindowSnapshot         `json:"started_window,omitempty"`
+	Durations     ValueSnapshot           `json:"durations"`
+	Events        ValueSnapshot           `json:"events"`
+	Bounces       int64                   `json:"bounces"`
+	BounceWindow  *WindowSnapshot         `json:"bounce_window,omitempty"`
+}
+
+// SessionState is the open session of one user.
+type SessionState struct {
+	Start  time.Time `json:"start"`
+	Last   time.Time `json:"last"`
+	Events int64     `json:"events"`
+}
+
+// FunnelSnapshot holds the step counts and the attempts in progress of one funnel.
+type FunnelSnapshot struct {
+	Steps   []string                  `json:"steps"`
+	Totals  []int64                   `json:"totals"`
+	Reached []*WindowSnapshot         `json:"reached"`
+	Users   map[string]FunnelProgress `json:"users,omitempty"`
+	Skipped int64                     `json:"skipped,omitempty"`
+}
+
+// FunnelProgress is the last step a user reached and when they started.
+type FunnelProgress struct {
+	Step    int       `json:"step"`
+	Started time.Time `json:"started"`
+}
+
+// DimensionSnapshot holds the breakdown of one event type for one dimension value.
+type DimensionSnapshot struct {
+	EventType string           `json:"event_type"`
+	Value     string           `json:"value"`
+	Count     int64            `json:"count"`
+	Values    ValueAggSnapshot `json:"values"`
+}
+
+// SketchSnapshot holds the HyperLogLog registers of the bucket starting at Start.
+type SketchSnapshot struct {
+	Start     time.Time `json:"start"`
+	Registers []byte    `json:"registers"`
+}
+
+// ValueSnapshot holds the value aggregates of one event type.
+type ValueSnapshot struct {
+	Total  ValueAggSnapshot      `json:"total"`
+	Window []ValueBucketSnapshot `json:"window,omitempty"`
+}
+
+// ValueAggSnapshot holds a count/sum/min/max aggregate.
+type ValueAggSnapshot struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+// ValueBucketSnapshot is the aggregate recorded in the bucket starting at Start.
+type ValueBucketSnapshot struct {
+	Start time.Time        `json:"start"`
+	Agg   ValueAggSnapshot `json:"agg"`
+}
+
+// MethodSnapshot holds the per-method request state.
+type MethodSnapshot struct {
+	Requests      int64           `json:"requests"`
+	Errors        int64           `json:"errors"`
+	Latency       *HistSnapshot   `json:"latency,omitempty"`
+	RequestWindow *WindowSnapshot `json:"request_window,omitempty"`
+	ErrorWindow   *WindowSnapshot `json:"error_window,omitempty"`
+}
+
+// WindowSnapshot holds the live buckets of a window ring.
+type WindowSnapshot struct {
+	Buckets []WindowBucket `json:"buckets"`
+}
+
+// WindowBucket is the count recorded in the bucket starting at Start.
+type WindowBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// HistSnapshot holds the state of a LatencyHist.
+type HistSnapshot struct {
+	Buckets []int64 `json:"buckets"`
+	Counts  []int64 `json:"counts"`
+	Total   int64   `json:"total"`
+	SumMs   int64   `json:"sum_ms"`
+	MinMs   int64   `json:"min_ms"`
+	MaxMs   int64   `json:"max_ms"`
+}
+
+// Snapshot returns a deep copy of the current store state.
+func (m *MetricStore) Snapshot() *Snapshot {
+	snap := &Snapshot{
+		Version:          SnapshotVersion,
+		TakenAt:          time.Now(),
+		TotalEvents:      m.totalEvents.Load(),
+		EventTypeCounts:  make(map[string]int64),
+		EventWindow:      m.eventWindow.snapshot(),
+		EventTypeWindows: make(map[string]*WindowSnapshot),
+		EventValues:      make(map[string]ValueSnapshot),
+		Methods:          make(map[string]MethodSnapshot),
+
+		UniqueUsers:       m.uniqueUsers.snapshot(),
+		UniqueUsersByType: make(map[string][]SketchSnapshot),
+
+		Dimensions: make(map[string][]DimensionSnapshot, len(m.dimensions)),
+		Funnels:    make(map[string]FunnelSnapshot, len(m.funnels)),
+	}
+
+	for key, d := range m.dimensions {
+		snap.Dimensions[key] = d.snapshot()
+	}
+	for name, f := range m.funnels {
+		snap.Funnels[name] = f.snapshot()
+	}
+	if m.sessions != nil {
+		snap.Sessions = m.sessions.snapshot()
+	}
+
+	m.eventTypeCounts.Range(func(key, value any) bool {
+		s := value.(*eventTypeStats)
+		snap.EventTypeCounts[key.(string)] = s.count.Load()
+		s.mu.Lock()
+		snap.EventTypeWindows[key.(string)] = s.window.snapshot()
+		s.mu.Unlock()
+		return true
+	})
+
+	m.eventValues.Range(func(key, value any) bool {
+		snap.EventValues[key.(string)] = value.(*valueStats).snapshot()
+		return true
+	})
+
+	m.uniqueUsersByType.Range(func(key, value any) bool {
+		snap.UniqueUsersByType[key.(string)] = value.(*uniqueUsers).snapshot()
+		return true
+	})
+
+	m.rangeMethods(func(name string, ms *methodStats) {
+		method := MethodSnapshot{
+			Requests: ms.reqs(),
+			Errors:   ms.errs(),
+		}
+		method.RequestWindow = ms.requests.snapshot()
+		method.ErrorWindow = ms.errors.snapshot()
+		if hist := ms.latency(m.buckets); hist.total > 0 {
+			method.Latency = hist.snapshot()
+		}
+		snap.Methods[name] = method
+	})
+
+	return snap
+}
+
+// NewMetricStoreFromSnapshot creates a metric store pre-populated with the
+// state captured in snap.
+func NewMetricStoreFromSnapshot(snap *Snapshot, opts Options) *MetricStore {
+	m := NewMetricStoreWithOptions(opts)
+
+	m.totalEvents.Store(snap.TotalEvents)
+	// types beyond the cap are merged into OtherEventType
+	for eventType, count := range snap.EventTypeCounts {
+		m.eventTypeStatsFor(m.typeKey(eventType)).count.Add(count)
+	}
+	for eventType, ws := range snap.EventTypeWindows {
+		m.eventTypeStatsFor(m.typeKey(eventType)).window.restore(ws)
+	}
+	m.eventWindow.restore(snap.EventWindow)
+	for eventType, vs := range snap.EventValues {
+		m.valueStatsFor(m.typeKey(eventType)).restore(vs)
+	}
+	m.uniqueUsers.restore(snap.UniqueUsers)
+	for eventType, sketches := range snap.UniqueUsersByType {
+		m.uniqueUsersFor(m.typeKey(eventType)).restore(sketches)
+	}
+	// dimensions no longer configured are dropped
+	for key, entries := range snap.Dimensions {
+		if d, ok := m.dimensions[key]; ok {
+			for i := range entries {
+				entries[i].EventType = m.typeKey(entries[i].EventType)
+			}
+			d.restore(entries)
+		}
+	}
+	// funnels no longer configured, or whose steps changed, start afresh
+	for name, fs := range snap.Funnels {
+		if f, ok := m.funnels[name]; ok && slices.Equal(f.def.Steps, fs.Steps) {
+			f.restore(fs)
+		}
+	}
+	if m.sessions != nil && snap.Sessions != nil {
+		m.sessions.restore(snap.Sessions)
+	}
+
+	for name, ms := range snap.Methods {
+		// restored counters go into the first stripe
+		method := m.method(name)
+		s := &method.stripes[0]
+		s.reqCount = ms.Requests
+		s.errCount = ms.Errors
+		if ms.Latency != nil {
+			s.latency = restoreHist(ms.Latency, m.buckets)
+		}
+		method.requests.restore(ms.RequestWindow)
+		method.errors.restore(ms.ErrorWindow)
+	}
+
+	return m
+}
+
+// WriteSnapshot atomically writes snap to path.
+func WriteSnapshot(path string, snap *Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+
+	// write to a temp file and rename so a crash never leaves a partial snapshot
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("fsync snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// ReadSnapshot reads a snapshot written by any supported format version.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("decode snapshot header: %w", err)
+	}
+
+	switch header.Version {
+	case 1:
+		old := &snapshotV1{}
+		if err := json.Unmarshal(data, old); err != nil {
+			return nil, fmt.Errorf("decode snapshot v1: %w", err)
+		}
+		return old.upgrade(), nil
+
+	case 2:
+		snap := &Snapshot{}
+		if err := json.Unmarshal(data, snap); err != nil {
+			return nil, fmt.Errorf("decode snapshot v2: %w", err)
+		}
+		return snap, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+}
+
+// snapshotV1 is the version 1 layout, which kept raw timestamps.
+type snapshotV1 struct {
+	TakenAt         time.Time        `json:"taken_at"`
+	WALSeq          uint64           `json:"wal_seq"`
+	TotalEvents     int64            `json:"total_events"`
+	EventTypeCounts map[string]int64 `json:"event_type_counts"`
+	EventTimestamps []time.Time      `json:"event_timestamps"`
+	Methods         map[string]struct {
+		Requests          int64         `json:"requests"`
+		Errors            int64         `json:"errors"`
+		Latency           *HistSnapshot `json:"latency,omitempty"`
+		RequestTimestamps []time.Time   `json:"request_timestamps,omitempty"`
+	} `json:"methods"`
+}
+
+// upgrade converts a v1 snapshot, turning each timestamp into a bucket.
+func (old *snapshotV1) upgrade() *Snapshot {
+	snap := &Snapshot{
+		Version:         SnapshotVersion,
+		TakenAt:         old.TakenAt,
+		WALSeq:          old.WALSeq,
+		TotalEvents:     old.TotalEvents,
+		EventTypeCounts: old.EventTypeCounts,
+		EventWindow:     timestampsToWindow(old.EventTimestamps),
+		Methods:         make(map[string]MethodSnapshot, len(old.Methods)),
+	}
+
+	for name, ms := range old.Methods {
+		snap.Methods[name] = MethodSnapshot{
+			Requests:      ms.Requests,
+			Errors:        ms.Errors,
+			Latency:       ms.Latency,
+			RequestWindow: timestampsToWindow(ms.RequestTimestamps),
+		}
+	}
+
+	return snap
+}
+
+func timestampsToWindow(timestamps []time.Time) *WindowSnapshot {
+	if len(timestamps) == 0 {
+		return nil
+	}
+	ws := &WindowSnapshot{Buckets: make([]WindowBucket, len(timestamps))}
+	for i, ts := range timestamps {
+		ws.Buckets[i] = WindowBucket{Start: ts, Count: 1}
+	}
+	return ws
+}
+
+func (r *bucketRing) snapshot() *WindowSnapshot {
+	ws := &WindowSnapshot{}
+	for _, b := range r.buckets {
+		if b.count == 0 {
+			continue
+		}
+		ws.Buckets = append(ws.Buckets, WindowBucket{
+			Start: time.Unix(0, b.epoch*int64(r.resolution)),
+			Count: b.count,
+		})
+	}
+	return ws
+}
+
+// restore adds the buckets of a snapshot to the ring. Buckets are placed by
+// start time, so a snapshot taken with another resolution still lands in
+// the right place.
+func (r *bucketRing) restore(ws *WindowSnapshot) {
+	if ws == nil {
+		return
+	}
+	for _, b := range ws.Buckets {
+		r.add(b.Start, b.Count)
+	}
+}
+
+func (vs *valueStats) snapshot() ValueSnapshot {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	return ValueSnapshot{Total: vs.total.snapshot(), Window: vs.window.snapshot()}
+}
+
+func (vs *valueStats) restore(snap ValueSnapshot) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.total.merge(restoreValueAgg(snap.Total))
+	vs.window.restore(snap.Window)
+}
+
+func (r *valueRing) snapshot() []ValueBucketSnapshot {
+	var out []ValueBucketSnapshot
+	for _, b := range r.buckets {
+		if b.agg.count == 0 {
+			continue
+		}
+		out = append(out, ValueBucketSnapshot{
+			Start: time.Unix(0, b.epoch*int64(r.resolution)),
+			Agg:   b.agg.snapshot(),
+		})
+	}
+	return out
+}
+
+func (r *valueRing) restore(buckets []ValueBucketSnapshot) {
+	for _, b := range buckets {
+		if agg := r.bucket(b.Start); agg != nil {
+			agg.merge(restoreValueAgg(b.Agg))
+		}
+	}
+}
+
+func (u *uniqueUsers) snapshot() []SketchSnapshot {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var out []SketchSnapshot
+	for _, b := range u.ring.buckets {
+		if b.sketch == nil {
+			continue
+		}
+		out = append(out, SketchSnapshot{
+			Start:     time.Unix(0, b.epoch*int64(u.ring.resolution)),
+			Registers: b.sketch.dense(),
+		})
+	}
+	return out
+}
+
+func (u *uniqueUsers) restore(sketches []SketchSnapshot) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, s := range sketches {
+		if len(s.Registers) != hllRegisters {
+			continue // written with a different precision
+		}
+		if sketch := u.ring.bucket(s.Start); sketch != nil {
+			sketch.merge(&hyperLogLog{registers: s.Registers})
+		}
+	}
+}
+
+func (d *dimension) snapshot() []DimensionSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]DimensionSnapshot, 0, len(d.stats))
+	for k, ds := range d.stats {
+		out = append(out, DimensionSnapshot{
+			EventType: k.eventType,
+			Value:     k.value,
+			Count:     ds.count,
+			Values:    ds.values.snapshot(),
+		})
+	}
+	return out
+}
+
+func (d *dimension) restore(entries []DimensionSnapshot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, e := range entries {
+		ds := d.statsFor(e.EventType, e.Value)
+		ds.count += e.Count
+		ds.values.merge(restoreValueAgg(e.Values))
+	}
+}
+
+func (a valueAgg) snapshot() ValueAggSnapshot {
+	return ValueAggSnapshot{Count: a.count, Sum: a.sum, Min: a.min, Max: a.max}
+}
+
+func restoreValueAgg(s ValueAggSnapshot) valueAgg {
+	return valueAgg{count: s.Count, sum: s.Sum, min: s.Min, max: s.Max}
+}
+
+func (h *LatencyHist) snapshot() *HistSnapshot {
+	return &HistSnapshot{
+		Buckets: append([]int64(nil), h.buckets...),
+		Counts:  append([]int64(nil), h.counts...),
+		Total:   h.total,
+		SumMs:   h.sumMs,
+		MinMs:   h.minMs,
+		MaxMs:   h.maxMs,
+	}
+}
+
+// restoreHist rebuilds a histogram from a snapshot. If the snapshot was
+// taken with different bucket bounds, counts are re-bucketed by upper bound.
+func restoreHist(hs *HistSnapshot, buckets []int64) *LatencyHist {
+	h := NewLatencyHist(buckets)
+	h.total = hs.Total
+	h.sumMs = hs.SumMs
+	h.minMs = hs.MinMs
+	h.maxMs = hs.MaxMs
+
+	for i, count := range hs.Counts {
+		if i >= len(hs.Buckets) {
+			break
+		}
+		h.counts[bucketIndex(buckets, hs.Buckets[i])] += count
+	}
+
+	return h
+}
+
+// bucketIndex returns the index of the first bucket that can hold ms,
+// falling back to the overflow bucket.
+func bucketIndex(buckets []int64, ms int64) int {
+	for i, upper := range buckets {
+		if ms <= upper {
+			return i
+		}
+	}
+	return len(buckets) - 1
+}
+
+func (f *funnel) snapshot() FunnelSnapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fs := FunnelSnapshot{
+		Steps:   f.def.Steps,
+		Totals:  append([]int64(nil), f.totals...),
+		Reached: make([]*WindowSnapshot, len(f.reached)),
+		Users:   make(map[string]FunnelProgress, len(f.users)),
+		Skipped: f.skipped,
+	}
+	for i, r := range f.reached {
+		fs.Reached[i] = r.snapshot()
+	}
+	for userID, p := range f.users {
+		fs.Users[userID] = FunnelProgress{Step: p.step, Started: p.started}
+	}
+	return fs
+}
+
+func (f *funnel) restore(fs FunnelSnapshot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	copy(f.totals, fs.Totals)
+	for i, ws := range fs.Reached {
+		if i < len(f.reached) {
+			f.reached[i].restore(ws)
+		}
+	}
+	for userID, p := range fs.Users {
+		f.track(userID, p.Step, p.Started)
+	}
+	f.skipped = fs.Skipped
+}
+
+func (s *sessionTracker) snapshot() *SessionSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss := &SessionSnapshot{
+		Open:          make(map[string]SessionState, len(s.open)),
+		Watermark:     s.watermark,
+		Untracked:     s.untracked,
+		Started:       s.started,
+		StartedWindow: s.startedWindow.snapshot(),
+		Durations:     ValueSnapshot{Total: s.durations.snapshot(), Window: s.durWindow.snapshot()},
+		Events:        ValueSnapshot{Total: s.events.snapshot(), Window: s.eventsWindow.snapshot()},
+		Bounces:       s.bounces,
+		BounceWindow:  s.bounceWindow.snapshot(),
+	}
+	for userID, sess := range s.open {
+		ss.Open[userID] = SessionState{Start: sess.start, Last: sess.last, Events: sess.events}
+	}
+	return ss
+}
+
+func (s *sessionTracker) restore(ss *SessionSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watermark = ss.Watermark
+	for userID, st := range ss.Open {
+		s.track(&session{userID: userID, start: st.Start, last: st.Last, events: st.Events})
+		// snapshots taken before the watermark was kept have none
+		if st.Last.After(s.watermark) {
+			s.watermark = st.Last
+		}
+	}
+	s.untracked = ss.Untracked
+	s.started = ss.Started
+	s.startedWindow.restore(ss.StartedWindow)
+	s.durations = restoreValueAgg(ss.Durations.Total)
+	s.durWindow.restore(ss.Durations.Window)
+	s.events = restoreValueAgg(ss.Events.Total)
+	s.eventsWindow.restore(ss.Events.Window)
+	s.bounces = ss.Bounces
+	s.bounceWindow.restore(ss.BounceWindow)
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var snapshotTestOptions = Options{
+	Window:     time.Minute,
+	Resolution: time.Second,
+	Retention:  time.Hour,
+	Dimensions: []string{"page"},
+	Funnels: []FunnelDefinition{
+		{Name: "signup", Steps: []string{"visit", "signup"}, Within: time.Hour},
+	},
+	SessionGap: 30 * time.Minute,
+}
+
+// populate records a bit of everything a snapshot holds.
+func populate(m *MetricStore, now time.Time) {
+	for i := 0; i < 20; i++ {
+		t := now.Add(-time.Duration(i) * time.Second)
+		user := fmt.Sprintf("u%d", i%4)
+		eventType := "visit"
+		if i%5 == 0 {
+			eventType = "signup"
+		}
+		m.AddEventsAt(eventType, 1, t)
+		m.RecordValuesAt(eventType, float64(i), 1, t)
+		m.RecordUserAt(eventType, user, t)
+		m.RecordFunnelEventAt(eventType, user, t)
+		m.RecordSessionEventAt(user, t)
+		m.RecordDimensionsN(eventType, map[string]string{"page": fmt.Sprintf("p%d", i%3)}, 1, float64(i), true)
+
+		method := "/analytics.IngestService/SendEvent"
+		m.RecordRequest(method)
+		m.RecordLatency(method, time.Duration(i)*time.Millisecond)
+		if i%7 == 0 {
+			m.RecordError(method)
+		}
+	}
+}
+
+// snapshotJSON encodes a snapshot without the time it was taken, with
+// dimension entries, which are kept in a map, sorted.
+func snapshotJSON(t *testing.T, snap *Snapshot) string {
+	t.Helper()
+	copied := *snap
+	copied.TakenAt = time.Time{}
+	for _, entries := range copied.Dimensions {
+		slices.SortFunc(entries, func(a, b DimensionSnapshot) int {
+			return cmp.Or(cmp.Compare(a.EventType, b.EventType), cmp.Compare(a.Value, b.Value))
+		})
+	}
+	data, err := json.Marshal(&copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	m := NewMetricStoreWithOptions(snapshotTestOptions)
+	populate(m, time.Now())
+
+	snap := m.Snapshot()
+	snap.WALSeq = 42
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := WriteSnapshot(path, snap); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+
+	read, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatalf("ReadSnapshot: %v", err)
+	}
+	if read.Version != SnapshotVersion || read.WALSeq != 42 {
+		t.Errorf("read version %d at WAL seq %d, want %d at 42", read.Version, read.WALSeq, SnapshotVersion)
+	}
+
+	restored := NewMetricStoreFromSnapshot(read, snapshotTestOptions)
+	again := restored.Snapshot()
+	again.WALSeq = read.WALSeq
+	if got, want := snapshotJSON(t, again), snapshotJSON(t, snap); got != want {
+		t.Errorf("restored store snapshots differently:\ngot  %s\nwant %s", got, want)
+	}
+
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"total events", restored.GetTotalEvents(), m.GetTotalEvents()},
+		{"events in window", restored.GetEventsInWindow(time.Minute), m.GetEventsInWindow(time.Minute)},
+		{"value stats", restored.GetValueStatsInWindow("visit", time.Minute), m.GetValueStatsInWindow("visit", time.Minute)},
+		{"unique users", restored.GetUniqueUsers(time.Minute), m.GetUniqueUsers(time.Minute)},
+		{"session stats", restored.GetSessionStats(), m.GetSessionStats()},
+		{"requests", restored.GetRequestCount("/analytics.IngestService/SendEvent"), m.GetRequestCount("/analytics.IngestService/SendEvent")},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: restored %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	gotFunnel, _ := restored.GetFunnel("signup", time.Minute)
+	wantFunnel, _ := m.GetFunnel("signup", time.Minute)
+	if fmt.Sprint(gotFunnel) != fmt.Sprint(wantFunnel) {
+		t.Errorf("funnel: restored %v, want %v", gotFunnel, wantFunnel)
+	}
+}
+
+func TestSnapshotUpgradeV1(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	v1 := map[string]any{
+		"version":           1,
+		"taken_at":          now,
+		"wal_seq":           7,
+		"total_events":      10,
+		"event_type_counts": map[string]int64{"visit": 6, "signup": 4},
+		"event_timestamps":  []time.Time{now.Add(-2 * time.Second), now.Add(-time.Second), now.Add(-time.Hour)},
+		"methods": map[string]any{
+			"/analytics.IngestService/SendEvent": map[string]any{
+				"requests":           5,
+				"errors":             1,
+				"request_timestamps": []time.Time{now.Add(-time.Second), now},
+			},
+		},
+	}
+	data, err := json.Marshal(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatalf("ReadSnapshot: %v", err)
+	}
+	if snap.Version != SnapshotVersion || snap.WALSeq != 7 {
+		t.Errorf("upgraded to version %d at WAL seq %d, want %d at 7", snap.Version, snap.WALSeq, SnapshotVersion)
+	}
+
+	m := NewMetricStoreFromSnapshot(snap, snapshotTestOptions)
+	checks := []struct {
+		name      string
+		got, want int64
+	}{
+		{"total events", m.GetTotalEvents(), 10},
+		{"visit events", m.GetEventTypeCount("visit"), 6},
+		{"events in window", m.GetEventsInWindow(time.Minute), 2},
+		{"requests", m.GetRequestCount("/analytics.IngestService/SendEvent"), 5},
+		{"errors", m.GetErrorCount("/analytics.IngestService/SendEvent"), 1},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %d, want %d", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestReadSnapshotUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSnapshot(path); err == nil {
+		t.Fatal("ReadSnapshot accepted an unknown version")
+	}
+}
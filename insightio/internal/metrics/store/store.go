@@ -2,37 +2,171 @@ package store
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// OtherEventType is the type that events are counted under once the store
+// tracks its maximum number of event types.
+const OtherEventType = "__other__"
+
+// DefaultMaxEventTypes is the number of event types tracked when no cap is
+// configured. Each type keeps windows over the whole retention, about 200 KB
+// at one hour and one second buckets.
+const DefaultMaxEventTypes = 200
+
 // Histogram buckets in milliseconds
 var DefaultBuckets = []int64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2000, 5000}
 
-// MetricStore is the main metrics storage structure
+// Options tunes a MetricStore. Zero values fall back to defaults.
+type Options struct {
+	Window           time.Duration // sliding window for windowed metrics
+	Resolution       time.Duration // width of a window bucket
+	SketchResolution time.Duration // width of a unique-user sketch bucket
+	Retention        time.Duration // longest window a query may ask for
+	FutureSkew       time.Duration // how far ahead of now event times may be
+	MaxEventTypes    int           // event types tracked, later ones count as OtherEventType
+
+	Dimensions           []string // metadata keys to break events down by
+	DimensionCardinality int      // distinct values tracked per dimension
+
+	Funnels     []FunnelDefinition // conversion funnels to track per user
+	FunnelUsers int                // users with a funnel in progress tracked per funnel
+
+	SessionGap   time.Duration // inactivity that ends a user's session, zero disables sessions
+	SessionUsers int           // users with an open session tracked
+}
+
+// MetricStore is the main metrics storage structure.
+// There is no store-wide lock: counters are atomics and per-method state is
+// striped, so interceptors, the worker and readers rarely contend.
 type MetricStore struct {
-	mu              sync.RWMutex
-	totalEvents     int64
-	eventTypeCounts map[string]int64
-	eventTimestamps []time.Time
-	windowSize      time.Duration
-	reqCount        map[string]int64
-	errCount        map[string]int64
-	latencyMap      map[string]*LatencyHist
-	buckets         []int64
-	reqTimestamps   map[string][]time.Time // method -> timestamps
+	totalEvents     atomic.Int64
+	eventTypeCounts sync.Map // event type -> *eventTypeStats
+	eventWindow     *syncRing
+	eventValues     sync.Map // event type -> *valueStats
+	methods         sync.Map // method -> *methodStats
+
+	uniqueUsers       *uniqueUsers
+	uniqueUsersByType sync.Map // event type -> *uniqueUsers
+
+	// event types come from clients, so the number tracked is capped
+	typesMu       sync.Mutex
+	eventTypes    int // types admitted, not counting OtherEventType
+	maxEventTypes int
+
+	dimensions map[string]*dimension // metadata key -> breakdown, fixed at creation
+	funnels    map[string]*funnel    // funnel name -> progress, fixed at creation
+	sessions   *sessionTracker       // nil when sessions are disabled
+
+	windowSize       time.Duration
+	retention        time.Duration
+	eventSpan        time.Duration // retention plus room for future event times
+	resolution       time.Duration
+	sketchResolution time.Duration
+	buckets          []int64
+	stripes          int
 }
 
 // NewMetricStore creates a new metrics store with the specified window size in seconds
 func NewMetricStore(windowSeconds int) *MetricStore {
-	return &MetricStore{
-		eventTypeCounts: make(map[string]int64),
-		eventTimestamps: make([]time.Time, 0, 1024),
-		windowSize:      time.Duration(windowSeconds) * time.Second,
+	return NewMetricStoreWithOptions(Options{
+		Window: time.Duration(windowSeconds) * time.Second,
+	})
+}
+
+// NewMetricStoreWithOptions creates a new metrics store with the given options
+func NewMetricStoreWithOptions(opts Options) *MetricStore {
+	if opts.Resolution <= 0 {
+		opts.Resolution = DefaultResolution
+	}
+	if opts.SketchResolution <= 0 {
+		opts.SketchResolution = DefaultSketchResolution
+	}
+	if opts.Retention < opts.Window {
+		opts.Retention = opts.Window
+	}
+
+	if opts.FutureSkew < 0 {
+		opts.FutureSkew = 0
+	}
+	if opts.MaxEventTypes <= 0 {
+		opts.MaxEventTypes = DefaultMaxEventTypes
+	}
 
-		reqCount:      make(map[string]int64),
-		errCount:      make(map[string]int64),
-		latencyMap:    make(map[string]*LatencyHist),
-		buckets:       DefaultBuckets,
-		reqTimestamps: make(map[string][]time.Time),
+	stripes := stripeCount()
+	eventSpan := opts.Retention + opts.FutureSkew
+	m := &MetricStore{
+		eventWindow:      newSyncRing(eventSpan, opts.Resolution),
+		windowSize:       opts.Window,
+		retention:        opts.Retention,
+		eventSpan:        eventSpan,
+		resolution:       opts.Resolution,
+		sketchResolution: opts.SketchResolution,
+		buckets:          DefaultBuckets,
+		stripes:          stripes,
+		maxEventTypes:    opts.MaxEventTypes,
+		dimensions:       make(map[string]*dimension, len(opts.Dimensions)),
+		funnels:          make(map[string]*funnel, len(opts.Funnels)),
 	}
+	m.uniqueUsers = m.newUniqueUsers()
+	for _, key := range opts.Dimensions {
+		m.dimensions[key] = newDimension(opts.DimensionCardinality)
+	}
+	for _, def := range opts.Funnels {
+		m.funnels[def.Name] = newFunnel(def, opts.FunnelUsers, eventSpan, opts.Resolution)
+	}
+	if opts.SessionGap > 0 {
+		m.sessions = newSessionTracker(opts.SessionGap, opts.SessionUsers, eventSpan, opts.Resolution)
+	}
+	return m
+}
+
+// Retention returns the longest window the store can answer.
+func (m *MetricStore) Retention() time.Duration {
+	return m.retention
+}
+
+// windowOrDefault returns window capped at the retention, or the store's
+// sliding window if window is zero.
+func (m *MetricStore) windowOrDefault(window time.Duration) time.Duration {
+	if window <= 0 {
+		return m.windowSize
+	}
+	if window > m.retention {
+		return m.retention
+	}
+	return window
+}
+
+// method returns the stats for a method, creating them on first use.
+func (m *MetricStore) method(name string) *methodStats {
+	if ms, ok := m.methods.Load(name); ok {
+		return ms.(*methodStats)
+	}
+	ms, _ := m.methods.LoadOrStore(name, newMethodStats(m.stripes, m.buckets, m.retention, m.resolution))
+	return ms.(*methodStats)
+}
+
+// HasMethod reports whether any request has been recorded for method.
+func (m *MetricStore) HasMethod(method string) bool {
+	_, ok := m.lookupMethod(method)
+	return ok
+}
+
+// lookupMethod returns the stats for a method if it has been seen.
+func (m *MetricStore) lookupMethod(name string) (*methodStats, bool) {
+	ms, ok := m.methods.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return ms.(*methodStats), true
+}
+
+// rangeMethods calls fn for every method seen so far.
+func (m *MetricStore) rangeMethods(fn func(name string, ms *methodStats)) {
+	m.methods.Range(func(key, value any) bool {
+		fn(key.(string), value.(*methodStats))
+		return true
+	})
 }
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Run with -cpu to see how throughput scales with GOMAXPROCS:
+//
+//	go test ./internal/metrics/store -run '^$' -bench . -cpu 1,2,4,8
+
+var benchMethods = []string{
+	"/analytics.IngestService/SendEvent",
+	"/analytics.IngestService/SendEventStream",
+	"/analytics.MetricsService/GetMetrics",
+	"/analytics.MetricsService/SubscribeMetrics",
+}
+
+// BenchmarkInterceptorPath mimics the unary interceptor: every call records
+// a request and its latency against one hot method.
+func BenchmarkInterceptorPath(b *testing.B) {
+	m := NewMetricStore(60)
+	method := benchMethods[0]
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.RecordRequest(method)
+			m.RecordLatency(method, 3*time.Millisecond)
+		}
+	})
+}
+
+// BenchmarkInterceptorPathMixedMethods spreads calls across several methods.
+func BenchmarkInterceptorPathMixedMethods(b *testing.B) {
+	m := NewMetricStore(60)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			method := benchMethods[i%len(benchMethods)]
+			m.RecordRequest(method)
+			m.RecordLatency(method, 3*time.Millisecond)
+			if i%50 == 0 {
+				m.RecordError(method)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkAddEvent records events across a handful of event types.
+func BenchmarkAddEvent(b *testing.B) {
+	m := NewMetricStore(60)
+	types := make([]string, 16)
+	for i := range types {
+		types[i] = "event_" + strconv.Itoa(i)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.AddEvent(types[i%len(types)])
+			i++
+		}
+	})
+}
+
+// BenchmarkReadWriteMix interleaves metric reads with writes, roughly one
+// read per hundred writes.
+func BenchmarkReadWriteMix(b *testing.B) {
+	m := NewMetricStore(60)
+	for _, method := range benchMethods {
+		m.RecordRequest(method)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			method := benchMethods[i%len(benchMethods)]
+			switch {
+			case i%100 == 0:
+				_ = m.GetTotalThroughput()
+				_ = m.GetTotalErrorRate()
+				_ = m.GetEventsPerWindow()
+			case i%2 == 0:
+				m.AddEvent("login")
+			default:
+				m.RecordRequest(method)
+				m.RecordLatency(method, time.Millisecond)
+			}
+			i++
+		}
+	})
+}
@@ -4,71 +4,62 @@ import "time"
 
 // RecordRequest records a request for a specific method
 func (m *MetricStore) RecordRequest(method string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	ms := m.method(method)
+	s := ms.stripe()
+	s.reqCount++
+	s.mu.Unlock()
 
-	m.reqCount[method]++
-	// Track timestamp for throughput calculation
-	now := time.Now()
-	m.reqTimestamps[method] = append(m.reqTimestamps[method], now)
+	// Track request in the method's window for throughput calculation
+	ms.requests.add(time.Now(), 1)
+}
 
-	// Clean up old timestamps outside the window to prevent memory growth
-	threshold := now.Add(-m.windowSize)
-	validTimestamps := make([]time.Time, 0, len(m.reqTimestamps[method]))
-	for _, ts := range m.reqTimestamps[method] {
-		if ts.After(threshold) {
-			validTimestamps = append(validTimestamps, ts)
-		}
+// GetRequestCount returns the number of requests recorded for a specific method
+func (m *MetricStore) GetRequestCount(method string) int64 {
+	ms, ok := m.lookupMethod(method)
+	if !ok {
+		return 0
 	}
-	m.reqTimestamps[method] = validTimestamps
+	return ms.reqs()
 }
 
 // GetThroughput returns requests per second for a specific method within the window
 func (m *MetricStore) GetThroughput(method string) float64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.GetThroughputInWindow(method, 0)
+}
 
-	timestamps, ok := m.reqTimestamps[method]
-	if !ok || len(timestamps) == 0 {
+// GetThroughputInWindow returns requests per second for a specific method
+// within the given window. A zero window uses the store's sliding window.
+func (m *MetricStore) GetThroughputInWindow(method string, window time.Duration) float64 {
+	ms, ok := m.lookupMethod(method)
+	if !ok {
 		return 0
 	}
 
-	// Count requests in the window
-	now := time.Now()
-	threshold := now.Add(-m.windowSize)
-	count := int64(0)
-	for _, ts := range timestamps {
-		if ts.After(threshold) {
-			count++
-		}
-	}
-
 	// Calculate requests per second
-	windowSeconds := m.windowSize.Seconds()
+	window = m.windowOrDefault(window)
+	windowSeconds := window.Seconds()
 	if windowSeconds <= 0 {
 		return 0
 	}
-	return float64(count) / windowSeconds
+	return float64(ms.requests.sum(time.Now(), window)) / windowSeconds
 }
 
 // GetTotalThroughput returns overall requests per second across all methods
 func (m *MetricStore) GetTotalThroughput() float64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.GetTotalThroughputInWindow(0)
+}
 
+// GetTotalThroughputInWindow returns overall requests per second across all
+// methods within the given window. A zero window uses the store's sliding window.
+func (m *MetricStore) GetTotalThroughputInWindow(window time.Duration) float64 {
+	window = m.windowOrDefault(window)
 	now := time.Now()
-	threshold := now.Add(-m.windowSize)
 	totalCount := int64(0)
+	m.rangeMethods(func(_ string, ms *methodStats) {
+		totalCount += ms.requests.sum(now, window)
+	})
 
-	for _, timestamps := range m.reqTimestamps {
-		for _, ts := range timestamps {
-			if ts.After(threshold) {
-				totalCount++
-			}
-		}
-	}
-
-	windowSeconds := m.windowSize.Seconds()
+	windowSeconds := window.Seconds()
 	if windowSeconds <= 0 {
 		return 0
 	}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// uniqueUsers holds the unique-user sketches of one event type, or of all
+// events for the store-wide sketch.
+type uniqueUsers struct {
+	mu   sync.Mutex
+	ring *sketchRing
+}
+
+func (u *uniqueUsers) add(t time.Time, hash uint64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ring.add(t, hash)
+}
+
+func (u *uniqueUsers) estimate(now time.Time, window time.Duration) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.ring.estimate(now, window)
+}
+
+// RecordUserAt records that userID sent an event of the given type at t.
+func (m *MetricStore) RecordUserAt(eventType, userID string, t time.Time) {
+	hash := hashUser(userID)
+
+	m.uniqueUsers.add(t, hash)
+	m.uniqueUsersFor(m.typeKey(eventType)).add(t, hash)
+}
+
+// GetUniqueUsers returns the estimated number of distinct users within window.
+// A zero window uses the store's sliding window.
+func (m *MetricStore) GetUniqueUsers(window time.Duration) int64 {
+	return m.uniqueUsers.estimate(time.Now(), m.windowOrDefault(window))
+}
+
+// GetUniqueUsersByType returns the estimated number of distinct users that
+// sent events of the given type within window.
+func (m *MetricStore) GetUniqueUsersByType(eventType string, window time.Duration) int64 {
+	u, ok := m.uniqueUsersByType.Load(eventType)
+	if !ok {
+		return 0
+	}
+	return u.(*uniqueUsers).estimate(time.Now(), m.windowOrDefault(window))
+}
+
+// uniqueUsersFor returns the sketches for an event type, creating them on first use.
+func (m *MetricStore) uniqueUsersFor(eventType string) *uniqueUsers {
+	if u, ok := m.uniqueUsersByType.Load(eventType); ok {
+		return u.(*uniqueUsers)
+	}
+	u, _ := m.uniqueUsersByType.LoadOrStore(eventType, m.newUniqueUsers())
+	return u.(*uniqueUsers)
+}
+
+func (m *MetricStore) newUniqueUsers() *uniqueUsers {
+	return &uniqueUsers{ring: newSketchRing(m.eventSpan, m.sketchResolution)}
+}
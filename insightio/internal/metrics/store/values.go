@@ -0,0 +1,175 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// ValueStats summarizes the numeric values carried by events of one type.
+type ValueStats struct {
+	Count int64
+	Sum   float64
+	Min   float64
+	Max   float64
+	Avg   float64
+}
+
+// valueAgg is a running count/sum/min/max.
+type valueAgg struct {
+	count int64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// observe records n events carrying the value v.
+func (a *valueAgg) observe(v float64, n int64) {
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.count += n
+	a.sum += v * float64(n)
+}
+
+func (a *valueAgg) merge(other valueAgg) {
+	if other.count == 0 {
+		return
+	}
+	if a.count == 0 || other.min < a.min {
+		a.min = other.min
+	}
+	if a.count == 0 || other.max > a.max {
+		a.max = other.max
+	}
+	a.count += other.count
+	a.sum += other.sum
+}
+
+func (a valueAgg) stats() ValueStats {
+	if a.count == 0 {
+		return ValueStats{}
+	}
+	return ValueStats{
+		Count: a.count,
+		Sum:   a.sum,
+		Min:   a.min,
+		Max:   a.max,
+		Avg:   a.sum / float64(a.count),
+	}
+}
+
+// valueRing keeps a valueAgg per time bucket, like bucketRing does for counts.
+type valueRing struct {
+	resolution time.Duration
+	buckets    []valueBucket
+}
+
+type valueBucket struct {
+	epoch int64
+	agg   valueAgg
+}
+
+func newValueRing(span, resolution time.Duration) *valueRing {
+	counts := newBucketRing(span, resolution)
+	return &valueRing{
+		resolution: counts.resolution,
+		buckets:    make([]valueBucket, len(counts.buckets)),
+	}
+}
+
+func (r *valueRing) epochOf(t time.Time) int64 {
+	return t.UnixNano() / int64(r.resolution)
+}
+
+// bucket returns the aggregate for the bucket holding t, recycling a stale
+// slot, or nil if t is older than anything the ring can hold.
+func (r *valueRing) bucket(t time.Time) *valueAgg {
+	epoch := r.epochOf(t)
+	b := &r.buckets[ringIndex(epoch, len(r.buckets))]
+	if b.epoch != epoch {
+		if b.epoch > epoch {
+			return nil
+		}
+		b.epoch = epoch
+		b.agg = valueAgg{}
+	}
+	return &b.agg
+}
+
+func (r *valueRing) observe(t time.Time, v float64, n int64) {
+	if agg := r.bucket(t); agg != nil {
+		agg.observe(v, n)
+	}
+}
+
+// aggregate merges the buckets in the window ending at now.
+func (r *valueRing) aggregate(now time.Time, window time.Duration) valueAgg {
+	newest := r.epochOf(now)
+
+	var out valueAgg
+	for epoch := newest - windowBuckets(window, r.resolution, len(r.buckets)) + 1; epoch <= newest; epoch++ {
+		if b := &r.buckets[ringIndex(epoch, len(r.buckets))]; b.epoch == epoch {
+			out.merge(b.agg)
+		}
+	}
+	return out
+}
+
+// valueStats holds the running and windowed aggregates of one event type.
+type valueStats struct {
+	mu     sync.Mutex
+	total  valueAgg
+	window *valueRing
+}
+
+// RecordValuesAt records n events carrying the same value that occurred at t.
+func (m *MetricStore) RecordValuesAt(eventType string, value float64, n int64, t time.Time) {
+	vs := m.valueStatsFor(m.typeKey(eventType))
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.total.observe(value, n)
+	vs.window.observe(t, value, n)
+}
+
+// GetValueStats returns the all-time value aggregates for an event type
+func (m *MetricStore) GetValueStats(eventType string) ValueStats {
+	vs, ok := m.eventValues.Load(eventType)
+	if !ok {
+		return ValueStats{}
+	}
+
+	v := vs.(*valueStats)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.total.stats()
+}
+
+// GetValueStatsInWindow returns the value aggregates for an event type within
+// the given window. A zero window uses the store's sliding window.
+func (m *MetricStore) GetValueStatsInWindow(eventType string, window time.Duration) ValueStats {
+	vs, ok := m.eventValues.Load(eventType)
+	if !ok {
+		return ValueStats{}
+	}
+
+	v := vs.(*valueStats)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.window.aggregate(time.Now(), m.windowOrDefault(window)).stats()
+}
+
+// valueStatsFor returns the value stats for an event type, creating them on first use.
+func (m *MetricStore) valueStatsFor(eventType string) *valueStats {
+	if vs, ok := m.eventValues.Load(eventType); ok {
+		return vs.(*valueStats)
+	}
+	vs, _ := m.eventValues.LoadOrStore(eventType, &valueStats{
+		window: newValueRing(m.eventSpan, m.resolution),
+	})
+	return vs.(*valueStats)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"log"
+	"math"
+
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/auth"
+	"github.com/ASHUTOSH-SWAIN-GIT/insightio/internal/ingest"
+	pb "github.com/ASHUTOSH-SWAIN-GIT/insightio/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EnableTail makes TailEvents stream the live events published to tail.
+// Raw events of every tenant go out, so only callers holding one of the
+// admin keys may tail; with none every call is refused.
+func (s *MetricsServiceServer) EnableTail(tail *ingest.Tail, adminKeys []string) {
+	s.tail = tail
+	s.tailAdmins = auth.NewAPIKeyValidator(adminKeys)
+}
+
+// TailEvents streams live events matching the request's filters. Events
+// are dropped for a client that cannot keep up, so tailing never slows
+// down ingestion.
+func (s *MetricsServiceServer) TailEvents(req *pb.TailEventsRequest, stream pb.MetricsService_TailEventsServer) error {
+	if s.tail == nil {
+		return status.Error(codes.Unimplemented, "event tail is disabled")
+	}
+	if err := s.tailAdmins.ValidateAPIKey(stream.Context()); err != nil {
+		return status.Error(codes.PermissionDenied, "admin API key required")
+	}
+	if req.SampleRate < 0 || req.SampleRate > 1 || math.IsNaN(req.SampleRate) {
+		return status.Error(codes.InvalidArgument, "sample_rate must be between 0 and 1")
+	}
+
+	sub := s.tail.Subscribe(ingest.TailFilter{
+		Type:       req.Type,
+		UserID:     req.UserId,
+		Metadata:   req.Metadata,
+		SampleRate: req.SampleRate,
+	}, ingest.DefaultTailBuffer)
+	defer func() {
+		sub.Close()
+		if dropped := sub.Dropped(); dropped > 0 {
+			log.Printf("Tail subscriber dropped %d events", dropped)
+		}
+	}()
+
+	for {
+		select {
+
+		case event := <-sub.Events():
+			if err := stream.Send(event); err != nil {
+				log.Println("Tail client disconnected:", err)
+				return err
+			}
+
+		case <-s.shutdown:
+			return errShuttingDown
+
+		case <-stream.Context().Done():
+			log.Println("Client stopped tailing events")
+			return nil
+		}
+	}
+}
@@ -109,28 +109,1048 @@ func (x *Event) GetValue() float64 {
 
 // Acknowledgement returned by RPCs.
 type Ack struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Ok      bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// per-event results, only set by SendEventStream; see BatchAck
+	Accepted      int32         `protobuf:"varint,3,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected      int32         `protobuf:"varint,4,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Duplicates    int32         `protobuf:"varint,5,opt,name=duplicates,proto3" json:"duplicates,omitempty"`
+	Filtered      int32         `protobuf:"varint,6,opt,name=filtered,proto3" json:"filtered,omitempty"`
+	Errors        []*EventError `protobuf:"bytes,7,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_analytics_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Ack) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *Ack) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Ack) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *Ack) GetRejected() int32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *Ack) GetDuplicates() int32 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *Ack) GetFiltered() int32 {
+	if x != nil {
+		return x.Filtered
+	}
+	return 0
+}
+
+func (x *Ack) GetErrors() []*EventError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+// Events sent together in one SendEventBatch call.
+type EventBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*Event               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventBatch) Reset() {
+	*x = EventBatch{}
+	mi := &file_analytics_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventBatch) ProtoMessage() {}
+
+func (x *EventBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventBatch.ProtoReflect.Descriptor instead.
+func (*EventBatch) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EventBatch) GetEvents() []*Event {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+// Why one event of a batch was not accepted.
+type EventError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // position of the event in the batch or stream
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Retryable     bool                   `protobuf:"varint,3,opt,name=retryable,proto3" json:"retryable,omitempty"` // the event may be accepted if sent again later
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventError) Reset() {
+	*x = EventError{}
+	mi := &file_analytics_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventError) ProtoMessage() {}
+
+func (x *EventError) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventError.ProtoReflect.Descriptor instead.
+func (*EventError) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EventError) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *EventError) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *EventError) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+// Result of a SendEventBatch call.
+type BatchAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      int32                  `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`     // events persisted, including duplicates
+	Rejected      int32                  `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`     // events listed in errors
+	Duplicates    int32                  `protobuf:"varint,3,opt,name=duplicates,proto3" json:"duplicates,omitempty"` // accepted events skipped because their id was seen recently
+	Errors        []*EventError          `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`          // ordered by index
+	Filtered      int32                  `protobuf:"varint,5,opt,name=filtered,proto3" json:"filtered,omitempty"`     // events dropped by a server-side filter, neither accepted nor rejected
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchAck) Reset() {
+	*x = BatchAck{}
+	mi := &file_analytics_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchAck) ProtoMessage() {}
+
+func (x *BatchAck) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchAck.ProtoReflect.Descriptor instead.
+func (*BatchAck) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchAck) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *BatchAck) GetRejected() int32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *BatchAck) GetDuplicates() int32 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *BatchAck) GetErrors() []*EventError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *BatchAck) GetFiltered() int32 {
+	if x != nil {
+		return x.Filtered
+	}
+	return 0
+}
+
+// Asks for the conversion of a configured funnel.
+type GetFunnelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	WindowSeconds int32                  `protobuf:"varint,2,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"` // attempts started within this window, 0 means the server default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFunnelRequest) Reset() {
+	*x = GetFunnelRequest{}
+	mi := &file_analytics_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFunnelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFunnelRequest) ProtoMessage() {}
+
+func (x *GetFunnelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFunnelRequest.ProtoReflect.Descriptor instead.
+func (*GetFunnelRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetFunnelRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetFunnelRequest) GetWindowSeconds() int32 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+// Attempts that reached one step of a funnel.
+type FunnelStep struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	EventType          string                 `protobuf:"bytes,1,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Count              int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	ConversionRate     float64                `protobuf:"fixed64,3,opt,name=conversion_rate,json=conversionRate,proto3" json:"conversion_rate,omitempty"`               // percentage 0-100 of attempts that reached this step
+	StepConversionRate float64                `protobuf:"fixed64,4,opt,name=step_conversion_rate,json=stepConversionRate,proto3" json:"step_conversion_rate,omitempty"` // percentage 0-100 of attempts at the previous step that reached this one
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *FunnelStep) Reset() {
+	*x = FunnelStep{}
+	mi := &file_analytics_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FunnelStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FunnelStep) ProtoMessage() {}
+
+func (x *FunnelStep) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FunnelStep.ProtoReflect.Descriptor instead.
+func (*FunnelStep) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FunnelStep) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *FunnelStep) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *FunnelStep) GetConversionRate() float64 {
+	if x != nil {
+		return x.ConversionRate
+	}
+	return 0
+}
+
+func (x *FunnelStep) GetStepConversionRate() float64 {
+	if x != nil {
+		return x.StepConversionRate
+	}
+	return 0
+}
+
+// Conversion through a funnel over a window.
+type FunnelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	WithinSeconds int32                  `protobuf:"varint,2,opt,name=within_seconds,json=withinSeconds,proto3" json:"within_seconds,omitempty"` // time allowed from the first step to the last
+	Steps         []*FunnelStep          `protobuf:"bytes,3,rep,name=steps,proto3" json:"steps,omitempty"`
+	Skipped       int64                  `protobuf:"varint,4,opt,name=skipped,proto3" json:"skipped,omitempty"`    // attempts not tracked because too many users were in progress
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // when the funnel was calculated
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FunnelResponse) Reset() {
+	*x = FunnelResponse{}
+	mi := &file_analytics_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FunnelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FunnelResponse) ProtoMessage() {}
+
+func (x *FunnelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FunnelResponse.ProtoReflect.Descriptor instead.
+func (*FunnelResponse) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FunnelResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FunnelResponse) GetWithinSeconds() int32 {
+	if x != nil {
+		return x.WithinSeconds
+	}
+	return 0
+}
+
+func (x *FunnelResponse) GetSteps() []*FunnelStep {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *FunnelResponse) GetSkipped() int64 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+func (x *FunnelResponse) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+// Constraints on events of one type, enforced at ingest.
+type EventSchema struct {
+	state            protoimpl.MessageState    `protogen:"open.v1"`
+	Type             string                    `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	RequiredMetadata []string                  `protobuf:"bytes,2,rep,name=required_metadata,json=requiredMetadata,proto3" json:"required_metadata,omitempty"`                             // metadata keys every event must carry
+	MinValue         *float64                  `protobuf:"fixed64,3,opt,name=min_value,json=minValue,proto3,oneof" json:"min_value,omitempty"`                                             // lowest allowed value, unset means unbounded
+	MaxValue         *float64                  `protobuf:"fixed64,4,opt,name=max_value,json=maxValue,proto3,oneof" json:"max_value,omitempty"`                                             // highest allowed value, unset means unbounded
+	Enums            map[string]*AllowedValues `protobuf:"bytes,5,rep,name=enums,proto3" json:"enums,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // metadata key -> values it may take
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *EventSchema) Reset() {
+	*x = EventSchema{}
+	mi := &file_analytics_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventSchema) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventSchema) ProtoMessage() {}
+
+func (x *EventSchema) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventSchema.ProtoReflect.Descriptor instead.
+func (*EventSchema) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EventSchema) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *EventSchema) GetRequiredMetadata() []string {
+	if x != nil {
+		return x.RequiredMetadata
+	}
+	return nil
+}
+
+func (x *EventSchema) GetMinValue() float64 {
+	if x != nil && x.MinValue != nil {
+		return *x.MinValue
+	}
+	return 0
+}
+
+func (x *EventSchema) GetMaxValue() float64 {
+	if x != nil && x.MaxValue != nil {
+		return *x.MaxValue
+	}
+	return 0
+}
+
+func (x *EventSchema) GetEnums() map[string]*AllowedValues {
+	if x != nil {
+		return x.Enums
+	}
+	return nil
+}
+
+// Values a metadata key may take.
+type AllowedValues struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllowedValues) Reset() {
+	*x = AllowedValues{}
+	mi := &file_analytics_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllowedValues) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllowedValues) ProtoMessage() {}
+
+func (x *AllowedValues) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllowedValues.ProtoReflect.Descriptor instead.
+func (*AllowedValues) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AllowedValues) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type ListSchemasRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSchemasRequest) Reset() {
+	*x = ListSchemasRequest{}
+	mi := &file_analytics_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSchemasRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSchemasRequest) ProtoMessage() {}
+
+func (x *ListSchemasRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSchemasRequest.ProtoReflect.Descriptor instead.
+func (*ListSchemasRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{10}
+}
+
+type ListSchemasResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Schemas       []*EventSchema         `protobuf:"bytes,1,rep,name=schemas,proto3" json:"schemas,omitempty"` // ordered by type
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`       // strict rejects violating events, warn only counts them
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSchemasResponse) Reset() {
+	*x = ListSchemasResponse{}
+	mi := &file_analytics_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSchemasResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSchemasResponse) ProtoMessage() {}
+
+func (x *ListSchemasResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSchemasResponse.ProtoReflect.Descriptor instead.
+func (*ListSchemasResponse) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListSchemasResponse) GetSchemas() []*EventSchema {
+	if x != nil {
+		return x.Schemas
+	}
+	return nil
+}
+
+func (x *ListSchemasResponse) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+type DeleteSchemaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSchemaRequest) Reset() {
+	*x = DeleteSchemaRequest{}
+	mi := &file_analytics_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSchemaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSchemaRequest) ProtoMessage() {}
+
+func (x *DeleteSchemaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSchemaRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSchemaRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteSchemaRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+// Describes what metrics the client wants.
+type GetMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MetricsNames  []string               `protobuf:"bytes,1,rep,name=metrics_names,json=metricsNames,proto3" json:"metrics_names,omitempty"`     // metrics to fetch, empty means all
+	WindowSeconds int32                  `protobuf:"varint,2,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"` // time window in seconds
+	GroupBy       string                 `protobuf:"bytes,3,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`                    // metadata dimension to break metrics down by
+	// SubscribeMetrics only
+	IntervalMs      int32   `protobuf:"varint,4,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`                 // push interval, 0 means the server default; clamped to the server's bounds
+	ChangeThreshold float64 `protobuf:"fixed64,5,opt,name=change_threshold,json=changeThreshold,proto3" json:"change_threshold,omitempty"` // push a metric only once it moved by more than this since last pushed, 0 pushes every tick
+	OnChange        bool    `protobuf:"varint,6,opt,name=on_change,json=onChange,proto3" json:"on_change,omitempty"`                       // push a metric only once it changed since last pushed, by more than change_threshold if set
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetMetricsRequest) Reset() {
+	*x = GetMetricsRequest{}
+	mi := &file_analytics_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricsRequest) ProtoMessage() {}
+
+func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetMetricsRequest) GetMetricsNames() []string {
+	if x != nil {
+		return x.MetricsNames
+	}
+	return nil
+}
+
+func (x *GetMetricsRequest) GetWindowSeconds() int32 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+func (x *GetMetricsRequest) GetIntervalMs() int32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetChangeThreshold() float64 {
+	if x != nil {
+		return x.ChangeThreshold
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetOnChange() bool {
+	if x != nil {
+		return x.OnChange
+	}
+	return false
+}
+
+// A single metric datapoint returned by metrics service.
+type Metric struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                                               // metric name
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`                                                                           // metric value
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                     // when metric was calculated
+	Labels        map[string]string      `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // dimension values for broken-down metrics
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_analytics_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Metric) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Metric) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Metric) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Metric) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// Multiple metrics in one response.
+type MetricResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetricResponse) Reset() {
+	*x = MetricResponse{}
+	mi := &file_analytics_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricResponse) ProtoMessage() {}
+
+func (x *MetricResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricResponse.ProtoReflect.Descriptor instead.
+func (*MetricResponse) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *MetricResponse) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+// Asks for the slowest endpoints.
+type GetEndpointStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TopK          int32                  `protobuf:"varint,1,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"` // number of endpoints to return, 0 means all
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEndpointStatsRequest) Reset() {
+	*x = GetEndpointStatsRequest{}
+	mi := &file_analytics_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEndpointStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEndpointStatsRequest) ProtoMessage() {}
+
+func (x *GetEndpointStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEndpointStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetEndpointStatsRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetEndpointStatsRequest) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+// Asks for the latency statistics of a single endpoint.
+type GetLatencyStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"` // full gRPC method name, e.g. /analytics.IngestService/SendEvent
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLatencyStatsRequest) Reset() {
+	*x = GetLatencyStatsRequest{}
+	mi := &file_analytics_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLatencyStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatencyStatsRequest) ProtoMessage() {}
+
+func (x *GetLatencyStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatencyStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetLatencyStatsRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetLatencyStatsRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+// Number of observations in one latency bucket.
+type LatencyBucket struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	UpperMs       int64                  `protobuf:"varint,1,opt,name=upper_ms,json=upperMs,proto3" json:"upper_ms,omitempty"` // bucket upper bound in milliseconds, the last bucket also holds overflow
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`                    // observations in this bucket
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Ack) Reset() {
-	*x = Ack{}
-	mi := &file_analytics_proto_msgTypes[1]
+func (x *LatencyBucket) Reset() {
+	*x = LatencyBucket{}
+	mi := &file_analytics_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Ack) String() string {
+func (x *LatencyBucket) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Ack) ProtoMessage() {}
+func (*LatencyBucket) ProtoMessage() {}
 
-func (x *Ack) ProtoReflect() protoreflect.Message {
-	mi := &file_analytics_proto_msgTypes[1]
+func (x *LatencyBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -141,49 +1161,181 @@ func (x *Ack) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
-func (*Ack) Descriptor() ([]byte, []int) {
-	return file_analytics_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use LatencyBucket.ProtoReflect.Descriptor instead.
+func (*LatencyBucket) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *Ack) GetOk() bool {
+func (x *LatencyBucket) GetUpperMs() int64 {
 	if x != nil {
-		return x.Ok
+		return x.UpperMs
 	}
-	return false
+	return 0
 }
 
-func (x *Ack) GetMessage() string {
+func (x *LatencyBucket) GetCount() int64 {
 	if x != nil {
-		return x.Message
+		return x.Count
+	}
+	return 0
+}
+
+// Request, error and latency statistics for one endpoint.
+type EndpointStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Requests      int64                  `protobuf:"varint,2,opt,name=requests,proto3" json:"requests,omitempty"`
+	Errors        int64                  `protobuf:"varint,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	ErrorRate     float64                `protobuf:"fixed64,4,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"` // percentage 0-100
+	Throughput    float64                `protobuf:"fixed64,5,opt,name=throughput,proto3" json:"throughput,omitempty"`                // requests per second within the server window
+	AvgMs         float64                `protobuf:"fixed64,6,opt,name=avg_ms,json=avgMs,proto3" json:"avg_ms,omitempty"`
+	MinMs         float64                `protobuf:"fixed64,7,opt,name=min_ms,json=minMs,proto3" json:"min_ms,omitempty"`
+	MaxMs         float64                `protobuf:"fixed64,8,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+	P50Ms         float64                `protobuf:"fixed64,9,opt,name=p50_ms,json=p50Ms,proto3" json:"p50_ms,omitempty"`
+	P95Ms         float64                `protobuf:"fixed64,10,opt,name=p95_ms,json=p95Ms,proto3" json:"p95_ms,omitempty"`
+	P99Ms         float64                `protobuf:"fixed64,11,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
+	Distribution  []*LatencyBucket       `protobuf:"bytes,12,rep,name=distribution,proto3" json:"distribution,omitempty"` // ordered by upper bound
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EndpointStats) Reset() {
+	*x = EndpointStats{}
+	mi := &file_analytics_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndpointStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointStats) ProtoMessage() {}
+
+func (x *EndpointStats) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointStats.ProtoReflect.Descriptor instead.
+func (*EndpointStats) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *EndpointStats) GetMethod() string {
+	if x != nil {
+		return x.Method
 	}
 	return ""
 }
 
-// Describes what metrics the client wants.
-type GetMetricsRequest struct {
+func (x *EndpointStats) GetRequests() int64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetErrors() int64 {
+	if x != nil {
+		return x.Errors
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetThroughput() float64 {
+	if x != nil {
+		return x.Throughput
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetAvgMs() float64 {
+	if x != nil {
+		return x.AvgMs
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetMinMs() float64 {
+	if x != nil {
+		return x.MinMs
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetMaxMs() float64 {
+	if x != nil {
+		return x.MaxMs
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetP50Ms() float64 {
+	if x != nil {
+		return x.P50Ms
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetP95Ms() float64 {
+	if x != nil {
+		return x.P95Ms
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetP99Ms() float64 {
+	if x != nil {
+		return x.P99Ms
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetDistribution() []*LatencyBucket {
+	if x != nil {
+		return x.Distribution
+	}
+	return nil
+}
+
+// Endpoints sorted by average latency, slowest first.
+type EndpointStatsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	MetricsNames  []string               `protobuf:"bytes,1,rep,name=metrics_names,json=metricsNames,proto3" json:"metrics_names,omitempty"`     // metrics to fetch, empty means all
-	WindowSeconds int32                  `protobuf:"varint,2,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"` // time window in seconds
+	Endpoints     []*EndpointStats       `protobuf:"bytes,1,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetMetricsRequest) Reset() {
-	*x = GetMetricsRequest{}
-	mi := &file_analytics_proto_msgTypes[2]
+func (x *EndpointStatsResponse) Reset() {
+	*x = EndpointStatsResponse{}
+	mi := &file_analytics_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetMetricsRequest) String() string {
+func (x *EndpointStatsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetMetricsRequest) ProtoMessage() {}
+func (*EndpointStatsResponse) ProtoMessage() {}
 
-func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_analytics_proto_msgTypes[2]
+func (x *EndpointStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -194,50 +1346,83 @@ func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
-func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
-	return file_analytics_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use EndpointStatsResponse.ProtoReflect.Descriptor instead.
+func (*EndpointStatsResponse) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *GetMetricsRequest) GetMetricsNames() []string {
+func (x *EndpointStatsResponse) GetEndpoints() []*EndpointStats {
 	if x != nil {
-		return x.MetricsNames
+		return x.Endpoints
 	}
 	return nil
 }
 
-func (x *GetMetricsRequest) GetWindowSeconds() int32 {
+// Asks for the catalog of metrics.
+type ListMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMetricsRequest) Reset() {
+	*x = ListMetricsRequest{}
+	mi := &file_analytics_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMetricsRequest) ProtoMessage() {}
+
+func (x *ListMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[21]
 	if x != nil {
-		return x.WindowSeconds
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-// A single metric datapoint returned by metrics service.
-type Metric struct {
+// Deprecated: Use ListMetricsRequest.ProtoReflect.Descriptor instead.
+func (*ListMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{21}
+}
+
+// Describes a metric that can be requested by name.
+type MetricDescriptor struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`           // metric name
-	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`       // metric value
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // when metric was calculated
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // name to request, per-type metrics as name:<type>
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Unit          string                 `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	Labels        []string               `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty"`                   // label keys set on the returned datapoints
+	PerType       bool                   `protobuf:"varint,5,opt,name=per_type,json=perType,proto3" json:"per_type,omitempty"` // reported per event type, * requests every type
+	Windowed      bool                   `protobuf:"varint,6,opt,name=windowed,proto3" json:"windowed,omitempty"`              // honors window_seconds
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Metric) Reset() {
-	*x = Metric{}
-	mi := &file_analytics_proto_msgTypes[3]
+func (x *MetricDescriptor) Reset() {
+	*x = MetricDescriptor{}
+	mi := &file_analytics_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Metric) String() string {
+func (x *MetricDescriptor) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Metric) ProtoMessage() {}
+func (*MetricDescriptor) ProtoMessage() {}
 
-func (x *Metric) ProtoReflect() protoreflect.Message {
-	mi := &file_analytics_proto_msgTypes[3]
+func (x *MetricDescriptor) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -248,55 +1433,76 @@ func (x *Metric) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
-func (*Metric) Descriptor() ([]byte, []int) {
-	return file_analytics_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use MetricDescriptor.ProtoReflect.Descriptor instead.
+func (*MetricDescriptor) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{22}
 }
 
-func (x *Metric) GetName() string {
+func (x *MetricDescriptor) GetName() string {
 	if x != nil {
 		return x.Name
 	}
 	return ""
 }
 
-func (x *Metric) GetValue() float64 {
+func (x *MetricDescriptor) GetDescription() string {
 	if x != nil {
-		return x.Value
+		return x.Description
 	}
-	return 0
+	return ""
 }
 
-func (x *Metric) GetTimestamp() *timestamppb.Timestamp {
+func (x *MetricDescriptor) GetUnit() string {
 	if x != nil {
-		return x.Timestamp
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *MetricDescriptor) GetLabels() []string {
+	if x != nil {
+		return x.Labels
 	}
 	return nil
 }
 
-// Multiple metrics in one response.
-type MetricResponse struct {
+func (x *MetricDescriptor) GetPerType() bool {
+	if x != nil {
+		return x.PerType
+	}
+	return false
+}
+
+func (x *MetricDescriptor) GetWindowed() bool {
+	if x != nil {
+		return x.Windowed
+	}
+	return false
+}
+
+// The catalog of metrics, sorted by name.
+type ListMetricsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	Metrics       []*MetricDescriptor    `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MetricResponse) Reset() {
-	*x = MetricResponse{}
-	mi := &file_analytics_proto_msgTypes[4]
+func (x *ListMetricsResponse) Reset() {
+	*x = ListMetricsResponse{}
+	mi := &file_analytics_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MetricResponse) String() string {
+func (x *ListMetricsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MetricResponse) ProtoMessage() {}
+func (*ListMetricsResponse) ProtoMessage() {}
 
-func (x *MetricResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_analytics_proto_msgTypes[4]
+func (x *ListMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -307,23 +1513,92 @@ func (x *MetricResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MetricResponse.ProtoReflect.Descriptor instead.
-func (*MetricResponse) Descriptor() ([]byte, []int) {
-	return file_analytics_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use ListMetricsResponse.ProtoReflect.Descriptor instead.
+func (*ListMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{23}
 }
 
-func (x *MetricResponse) GetMetrics() []*Metric {
+func (x *ListMetricsResponse) GetMetrics() []*MetricDescriptor {
 	if x != nil {
 		return x.Metrics
 	}
 	return nil
 }
 
+// Selects the live events streamed by TailEvents. Empty fields match everything.
+type TailEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`                                                                                   // only events of this type
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                                                                 // only events from this user
+	Metadata      map[string]string      `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // only events carrying every key/value pair
+	SampleRate    float64                `protobuf:"fixed64,4,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`                                                   // fraction of matching events to send, 0 means all
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailEventsRequest) Reset() {
+	*x = TailEventsRequest{}
+	mi := &file_analytics_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailEventsRequest) ProtoMessage() {}
+
+func (x *TailEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analytics_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailEventsRequest.ProtoReflect.Descriptor instead.
+func (*TailEventsRequest) Descriptor() ([]byte, []int) {
+	return file_analytics_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *TailEventsRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TailEventsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *TailEventsRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *TailEventsRequest) GetSampleRate() float64 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
 var File_analytics_proto protoreflect.FileDescriptor
 
 const file_analytics_proto_rawDesc = "" +
 	"\n" +
-	"\x0fanalytics.proto\x12\tanalytics\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8d\x02\n" +
+	"\x0fanalytics.proto\x12\tanalytics\x1a\x1fgoogle/protobuf/timestamp.proto\"\x93\x02\n" +
 	"\x05Event\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x128\n" +
@@ -333,26 +1608,152 @@ const file_analytics_proto_rawDesc = "" +
 	"\x05value\x18\x06 \x01(\x01R\x05value\x1a;\n" +
 	"\rMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"/\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01J\x04\b\a\x10\b\"\xd2\x01\n" +
 	"\x03Ack\x12\x0e\n" +
 	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"_\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\baccepted\x18\x03 \x01(\x05R\baccepted\x12\x1a\n" +
+	"\brejected\x18\x04 \x01(\x05R\brejected\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x05 \x01(\x05R\n" +
+	"duplicates\x12\x1a\n" +
+	"\bfiltered\x18\x06 \x01(\x05R\bfiltered\x12-\n" +
+	"\x06errors\x18\a \x03(\v2\x15.analytics.EventErrorR\x06errors\"6\n" +
+	"\n" +
+	"EventBatch\x12(\n" +
+	"\x06events\x18\x01 \x03(\v2\x10.analytics.EventR\x06events\"X\n" +
+	"\n" +
+	"EventError\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x1c\n" +
+	"\tretryable\x18\x03 \x01(\bR\tretryable\"\xad\x01\n" +
+	"\bBatchAck\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x05R\baccepted\x12\x1a\n" +
+	"\brejected\x18\x02 \x01(\x05R\brejected\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x03 \x01(\x05R\n" +
+	"duplicates\x12-\n" +
+	"\x06errors\x18\x04 \x03(\v2\x15.analytics.EventErrorR\x06errors\x12\x1a\n" +
+	"\bfiltered\x18\x05 \x01(\x05R\bfiltered\"M\n" +
+	"\x10GetFunnelRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12%\n" +
+	"\x0ewindow_seconds\x18\x02 \x01(\x05R\rwindowSeconds\"\x9c\x01\n" +
+	"\n" +
+	"FunnelStep\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x01 \x01(\tR\teventType\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12'\n" +
+	"\x0fconversion_rate\x18\x03 \x01(\x01R\x0econversionRate\x120\n" +
+	"\x14step_conversion_rate\x18\x04 \x01(\x01R\x12stepConversionRate\"\xcc\x01\n" +
+	"\x0eFunnelResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12%\n" +
+	"\x0ewithin_seconds\x18\x02 \x01(\x05R\rwithinSeconds\x12+\n" +
+	"\x05steps\x18\x03 \x03(\v2\x15.analytics.FunnelStepR\x05steps\x12\x18\n" +
+	"\askipped\x18\x04 \x01(\x03R\askipped\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xbb\x02\n" +
+	"\vEventSchema\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12+\n" +
+	"\x11required_metadata\x18\x02 \x03(\tR\x10requiredMetadata\x12 \n" +
+	"\tmin_value\x18\x03 \x01(\x01H\x00R\bminValue\x88\x01\x01\x12 \n" +
+	"\tmax_value\x18\x04 \x01(\x01H\x01R\bmaxValue\x88\x01\x01\x127\n" +
+	"\x05enums\x18\x05 \x03(\v2!.analytics.EventSchema.EnumsEntryR\x05enums\x1aR\n" +
+	"\n" +
+	"EnumsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.analytics.AllowedValuesR\x05value:\x028\x01B\f\n" +
+	"\n" +
+	"_min_valueB\f\n" +
+	"\n" +
+	"_max_value\"'\n" +
+	"\rAllowedValues\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"\x14\n" +
+	"\x12ListSchemasRequest\"[\n" +
+	"\x13ListSchemasResponse\x120\n" +
+	"\aschemas\x18\x01 \x03(\v2\x16.analytics.EventSchemaR\aschemas\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\")\n" +
+	"\x13DeleteSchemaRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\"\xe3\x01\n" +
 	"\x11GetMetricsRequest\x12#\n" +
 	"\rmetrics_names\x18\x01 \x03(\tR\fmetricsNames\x12%\n" +
-	"\x0ewindow_seconds\x18\x02 \x01(\x05R\rwindowSeconds\"l\n" +
+	"\x0ewindow_seconds\x18\x02 \x01(\x05R\rwindowSeconds\x12\x19\n" +
+	"\bgroup_by\x18\x03 \x01(\tR\agroupBy\x12\x1f\n" +
+	"\vinterval_ms\x18\x04 \x01(\x05R\n" +
+	"intervalMs\x12)\n" +
+	"\x10change_threshold\x18\x05 \x01(\x01R\x0fchangeThreshold\x12\x1b\n" +
+	"\ton_change\x18\x06 \x01(\bR\bonChange\"\xde\x01\n" +
 	"\x06Metric\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\x01R\x05value\x128\n" +
-	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"=\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x125\n" +
+	"\x06labels\x18\x04 \x03(\v2\x1d.analytics.Metric.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"=\n" +
 	"\x0eMetricResponse\x12+\n" +
-	"\ametrics\x18\x01 \x03(\v2\x11.analytics.MetricR\ametrics2u\n" +
+	"\ametrics\x18\x01 \x03(\v2\x11.analytics.MetricR\ametrics\".\n" +
+	"\x17GetEndpointStatsRequest\x12\x13\n" +
+	"\x05top_k\x18\x01 \x01(\x05R\x04topK\"0\n" +
+	"\x16GetLatencyStatsRequest\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\"@\n" +
+	"\rLatencyBucket\x12\x19\n" +
+	"\bupper_ms\x18\x01 \x01(\x03R\aupperMs\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"\xe2\x02\n" +
+	"\rEndpointStats\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x1a\n" +
+	"\brequests\x18\x02 \x01(\x03R\brequests\x12\x16\n" +
+	"\x06errors\x18\x03 \x01(\x03R\x06errors\x12\x1d\n" +
+	"\n" +
+	"error_rate\x18\x04 \x01(\x01R\terrorRate\x12\x1e\n" +
+	"\n" +
+	"throughput\x18\x05 \x01(\x01R\n" +
+	"throughput\x12\x15\n" +
+	"\x06avg_ms\x18\x06 \x01(\x01R\x05avgMs\x12\x15\n" +
+	"\x06min_ms\x18\a \x01(\x01R\x05minMs\x12\x15\n" +
+	"\x06max_ms\x18\b \x01(\x01R\x05maxMs\x12\x15\n" +
+	"\x06p50_ms\x18\t \x01(\x01R\x05p50Ms\x12\x15\n" +
+	"\x06p95_ms\x18\n" +
+	" \x01(\x01R\x05p95Ms\x12\x15\n" +
+	"\x06p99_ms\x18\v \x01(\x01R\x05p99Ms\x12<\n" +
+	"\fdistribution\x18\f \x03(\v2\x18.analytics.LatencyBucketR\fdistribution\"O\n" +
+	"\x15EndpointStatsResponse\x126\n" +
+	"\tendpoints\x18\x01 \x03(\v2\x18.analytics.EndpointStatsR\tendpoints\"\x14\n" +
+	"\x12ListMetricsRequest\"\xab\x01\n" +
+	"\x10MetricDescriptor\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04unit\x18\x03 \x01(\tR\x04unit\x12\x16\n" +
+	"\x06labels\x18\x04 \x03(\tR\x06labels\x12\x19\n" +
+	"\bper_type\x18\x05 \x01(\bR\aperType\x12\x1a\n" +
+	"\bwindowed\x18\x06 \x01(\bR\bwindowed\"L\n" +
+	"\x13ListMetricsResponse\x125\n" +
+	"\ametrics\x18\x01 \x03(\v2\x1b.analytics.MetricDescriptorR\ametrics\"\xe6\x01\n" +
+	"\x11TailEventsRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12F\n" +
+	"\bmetadata\x18\x03 \x03(\v2*.analytics.TailEventsRequest.MetadataEntryR\bmetadata\x12\x1f\n" +
+	"\vsample_rate\x18\x04 \x01(\x01R\n" +
+	"sampleRate\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xb3\x01\n" +
 	"\rIngestService\x12-\n" +
 	"\tSendEvent\x12\x10.analytics.Event\x1a\x0e.analytics.Ack\x125\n" +
-	"\x0fSendEventStream\x12\x10.analytics.Event\x1a\x0e.analytics.Ack(\x012\x9e\x01\n" +
+	"\x0fSendEventStream\x12\x10.analytics.Event\x1a\x0e.analytics.Ack(\x01\x12<\n" +
+	"\x0eSendEventBatch\x12\x15.analytics.EventBatch\x1a\x13.analytics.BatchAck2\xd2\x01\n" +
+	"\rSchemaService\x12L\n" +
+	"\vListSchemas\x12\x1d.analytics.ListSchemasRequest\x1a\x1e.analytics.ListSchemasResponse\x123\n" +
+	"\tPutSchema\x12\x16.analytics.EventSchema\x1a\x0e.analytics.Ack\x12>\n" +
+	"\fDeleteSchema\x12\x1e.analytics.DeleteSchemaRequest\x1a\x0e.analytics.Ack2\x9b\x04\n" +
 	"\x0eMetricsService\x12E\n" +
 	"\n" +
 	"GetMetrics\x12\x1c.analytics.GetMetricsRequest\x1a\x19.analytics.MetricResponse\x12E\n" +
-	"\x10SubscribeMetrics\x12\x1c.analytics.GetMetricsRequest\x1a\x11.analytics.Metric0\x01B/Z-github.com/ASHUTOSH-SWAIN-GIT/insightio/protob\x06proto3"
+	"\x10SubscribeMetrics\x12\x1c.analytics.GetMetricsRequest\x1a\x11.analytics.Metric0\x01\x12X\n" +
+	"\x10GetEndpointStats\x12\".analytics.GetEndpointStatsRequest\x1a .analytics.EndpointStatsResponse\x12N\n" +
+	"\x0fGetLatencyStats\x12!.analytics.GetLatencyStatsRequest\x1a\x18.analytics.EndpointStats\x12L\n" +
+	"\vListMetrics\x12\x1d.analytics.ListMetricsRequest\x1a\x1e.analytics.ListMetricsResponse\x12>\n" +
+	"\n" +
+	"TailEvents\x12\x1c.analytics.TailEventsRequest\x1a\x10.analytics.Event0\x01\x12C\n" +
+	"\tGetFunnel\x12\x1b.analytics.GetFunnelRequest\x1a\x19.analytics.FunnelResponseB/Z-github.com/ASHUTOSH-SWAIN-GIT/insightio/protob\x06proto3"
 
 var (
 	file_analytics_proto_rawDescOnce sync.Once
@@ -366,34 +1767,88 @@ func file_analytics_proto_rawDescGZIP() []byte {
 	return file_analytics_proto_rawDescData
 }
 
-var file_analytics_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_analytics_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
 var file_analytics_proto_goTypes = []any{
-	(*Event)(nil),                 // 0: analytics.Event
-	(*Ack)(nil),                   // 1: analytics.Ack
-	(*GetMetricsRequest)(nil),     // 2: analytics.GetMetricsRequest
-	(*Metric)(nil),                // 3: analytics.Metric
-	(*MetricResponse)(nil),        // 4: analytics.MetricResponse
-	nil,                           // 5: analytics.Event.MetadataEntry
-	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+	(*Event)(nil),                   // 0: analytics.Event
+	(*Ack)(nil),                     // 1: analytics.Ack
+	(*EventBatch)(nil),              // 2: analytics.EventBatch
+	(*EventError)(nil),              // 3: analytics.EventError
+	(*BatchAck)(nil),                // 4: analytics.BatchAck
+	(*GetFunnelRequest)(nil),        // 5: analytics.GetFunnelRequest
+	(*FunnelStep)(nil),              // 6: analytics.FunnelStep
+	(*FunnelResponse)(nil),          // 7: analytics.FunnelResponse
+	(*EventSchema)(nil),             // 8: analytics.EventSchema
+	(*AllowedValues)(nil),           // 9: analytics.AllowedValues
+	(*ListSchemasRequest)(nil),      // 10: analytics.ListSchemasRequest
+	(*ListSchemasResponse)(nil),     // 11: analytics.ListSchemasResponse
+	(*DeleteSchemaRequest)(nil),     // 12: analytics.DeleteSchemaRequest
+	(*GetMetricsRequest)(nil),       // 13: analytics.GetMetricsRequest
+	(*Metric)(nil),                  // 14: analytics.Metric
+	(*MetricResponse)(nil),          // 15: analytics.MetricResponse
+	(*GetEndpointStatsRequest)(nil), // 16: analytics.GetEndpointStatsRequest
+	(*GetLatencyStatsRequest)(nil),  // 17: analytics.GetLatencyStatsRequest
+	(*LatencyBucket)(nil),           // 18: analytics.LatencyBucket
+	(*EndpointStats)(nil),           // 19: analytics.EndpointStats
+	(*EndpointStatsResponse)(nil),   // 20: analytics.EndpointStatsResponse
+	(*ListMetricsRequest)(nil),      // 21: analytics.ListMetricsRequest
+	(*MetricDescriptor)(nil),        // 22: analytics.MetricDescriptor
+	(*ListMetricsResponse)(nil),     // 23: analytics.ListMetricsResponse
+	(*TailEventsRequest)(nil),       // 24: analytics.TailEventsRequest
+	nil,                             // 25: analytics.Event.MetadataEntry
+	nil,                             // 26: analytics.EventSchema.EnumsEntry
+	nil,                             // 27: analytics.Metric.LabelsEntry
+	nil,                             // 28: analytics.TailEventsRequest.MetadataEntry
+	(*timestamppb.Timestamp)(nil),   // 29: google.protobuf.Timestamp
 }
 var file_analytics_proto_depIdxs = []int32{
-	6, // 0: analytics.Event.timestamp:type_name -> google.protobuf.Timestamp
-	5, // 1: analytics.Event.metadata:type_name -> analytics.Event.MetadataEntry
-	6, // 2: analytics.Metric.timestamp:type_name -> google.protobuf.Timestamp
-	3, // 3: analytics.MetricResponse.metrics:type_name -> analytics.Metric
-	0, // 4: analytics.IngestService.SendEvent:input_type -> analytics.Event
-	0, // 5: analytics.IngestService.SendEventStream:input_type -> analytics.Event
-	2, // 6: analytics.MetricsService.GetMetrics:input_type -> analytics.GetMetricsRequest
-	2, // 7: analytics.MetricsService.SubscribeMetrics:input_type -> analytics.GetMetricsRequest
-	1, // 8: analytics.IngestService.SendEvent:output_type -> analytics.Ack
-	1, // 9: analytics.IngestService.SendEventStream:output_type -> analytics.Ack
-	4, // 10: analytics.MetricsService.GetMetrics:output_type -> analytics.MetricResponse
-	3, // 11: analytics.MetricsService.SubscribeMetrics:output_type -> analytics.Metric
-	8, // [8:12] is the sub-list for method output_type
-	4, // [4:8] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	29, // 0: analytics.Event.timestamp:type_name -> google.protobuf.Timestamp
+	25, // 1: analytics.Event.metadata:type_name -> analytics.Event.MetadataEntry
+	3,  // 2: analytics.Ack.errors:type_name -> analytics.EventError
+	0,  // 3: analytics.EventBatch.events:type_name -> analytics.Event
+	3,  // 4: analytics.BatchAck.errors:type_name -> analytics.EventError
+	6,  // 5: analytics.FunnelResponse.steps:type_name -> analytics.FunnelStep
+	29, // 6: analytics.FunnelResponse.timestamp:type_name -> google.protobuf.Timestamp
+	26, // 7: analytics.EventSchema.enums:type_name -> analytics.EventSchema.EnumsEntry
+	8,  // 8: analytics.ListSchemasResponse.schemas:type_name -> analytics.EventSchema
+	29, // 9: analytics.Metric.timestamp:type_name -> google.protobuf.Timestamp
+	27, // 10: analytics.Metric.labels:type_name -> analytics.Metric.LabelsEntry
+	14, // 11: analytics.MetricResponse.metrics:type_name -> analytics.Metric
+	18, // 12: analytics.EndpointStats.distribution:type_name -> analytics.LatencyBucket
+	19, // 13: analytics.EndpointStatsResponse.endpoints:type_name -> analytics.EndpointStats
+	22, // 14: analytics.ListMetricsResponse.metrics:type_name -> analytics.MetricDescriptor
+	28, // 15: analytics.TailEventsRequest.metadata:type_name -> analytics.TailEventsRequest.MetadataEntry
+	9,  // 16: analytics.EventSchema.EnumsEntry.value:type_name -> analytics.AllowedValues
+	0,  // 17: analytics.IngestService.SendEvent:input_type -> analytics.Event
+	0,  // 18: analytics.IngestService.SendEventStream:input_type -> analytics.Event
+	2,  // 19: analytics.IngestService.SendEventBatch:input_type -> analytics.EventBatch
+	10, // 20: analytics.SchemaService.ListSchemas:input_type -> analytics.ListSchemasRequest
+	8,  // 21: analytics.SchemaService.PutSchema:input_type -> analytics.EventSchema
+	12, // 22: analytics.SchemaService.DeleteSchema:input_type -> analytics.DeleteSchemaRequest
+	13, // 23: analytics.MetricsService.GetMetrics:input_type -> analytics.GetMetricsRequest
+	13, // 24: analytics.MetricsService.SubscribeMetrics:input_type -> analytics.GetMetricsRequest
+	16, // 25: analytics.MetricsService.GetEndpointStats:input_type -> analytics.GetEndpointStatsRequest
+	17, // 26: analytics.MetricsService.GetLatencyStats:input_type -> analytics.GetLatencyStatsRequest
+	21, // 27: analytics.MetricsService.ListMetrics:input_type -> analytics.ListMetricsRequest
+	24, // 28: analytics.MetricsService.TailEvents:input_type -> analytics.TailEventsRequest
+	5,  // 29: analytics.MetricsService.GetFunnel:input_type -> analytics.GetFunnelRequest
+	1,  // 30: analytics.IngestService.SendEvent:output_type -> analytics.Ack
+	1,  // 31: analytics.IngestService.SendEventStream:output_type -> analytics.Ack
+	4,  // 32: analytics.IngestService.SendEventBatch:output_type -> analytics.BatchAck
+	11, // 33: analytics.SchemaService.ListSchemas:output_type -> analytics.ListSchemasResponse
+	1,  // 34: analytics.SchemaService.PutSchema:output_type -> analytics.Ack
+	1,  // 35: analytics.SchemaService.DeleteSchema:output_type -> analytics.Ack
+	15, // 36: analytics.MetricsService.GetMetrics:output_type -> analytics.MetricResponse
+	14, // 37: analytics.MetricsService.SubscribeMetrics:output_type -> analytics.Metric
+	20, // 38: analytics.MetricsService.GetEndpointStats:output_type -> analytics.EndpointStatsResponse
+	19, // 39: analytics.MetricsService.GetLatencyStats:output_type -> analytics.EndpointStats
+	23, // 40: analytics.MetricsService.ListMetrics:output_type -> analytics.ListMetricsResponse
+	0,  // 41: analytics.MetricsService.TailEvents:output_type -> analytics.Event
+	7,  // 42: analytics.MetricsService.GetFunnel:output_type -> analytics.FunnelResponse
+	30, // [30:43] is the sub-list for method output_type
+	17, // [17:30] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_analytics_proto_init() }
@@ -401,15 +1856,16 @@ func file_analytics_proto_init() {
 	if File_analytics_proto != nil {
 		return
 	}
+	file_analytics_proto_msgTypes[8].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_analytics_proto_rawDesc), len(file_analytics_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   29,
 			NumExtensions: 0,
-			NumServices:   2,
+			NumServices:   3,
 		},
 		GoTypes:           file_analytics_proto_goTypes,
 		DependencyIndexes: file_analytics_proto_depIdxs,
@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	IngestService_SendEvent_FullMethodName       = "/analytics.IngestService/SendEvent"
 	IngestService_SendEventStream_FullMethodName = "/analytics.IngestService/SendEventStream"
+	IngestService_SendEventBatch_FullMethodName  = "/analytics.IngestService/SendEventBatch"
 )
 
 // IngestServiceClient is the client API for IngestService service.
@@ -31,6 +32,7 @@ const (
 type IngestServiceClient interface {
 	SendEvent(ctx context.Context, in *Event, opts ...grpc.CallOption) (*Ack, error)
 	SendEventStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Event, Ack], error)
+	SendEventBatch(ctx context.Context, in *EventBatch, opts ...grpc.CallOption) (*BatchAck, error)
 }
 
 type ingestServiceClient struct {
@@ -64,6 +66,16 @@ func (c *ingestServiceClient) SendEventStream(ctx context.Context, opts ...grpc.
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type IngestService_SendEventStreamClient = grpc.ClientStreamingClient[Event, Ack]
 
+func (c *ingestServiceClient) SendEventBatch(ctx context.Context, in *EventBatch, opts ...grpc.CallOption) (*BatchAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchAck)
+	err := c.cc.Invoke(ctx, IngestService_SendEventBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IngestServiceServer is the server API for IngestService service.
 // All implementations must embed UnimplementedIngestServiceServer
 // for forward compatibility.
@@ -72,6 +84,7 @@ type IngestService_SendEventStreamClient = grpc.ClientStreamingClient[Event, Ack
 type IngestServiceServer interface {
 	SendEvent(context.Context, *Event) (*Ack, error)
 	SendEventStream(grpc.ClientStreamingServer[Event, Ack]) error
+	SendEventBatch(context.Context, *EventBatch) (*BatchAck, error)
 	mustEmbedUnimplementedIngestServiceServer()
 }
 
@@ -88,6 +101,9 @@ func (UnimplementedIngestServiceServer) SendEvent(context.Context, *Event) (*Ack
 func (UnimplementedIngestServiceServer) SendEventStream(grpc.ClientStreamingServer[Event, Ack]) error {
 	return status.Error(codes.Unimplemented, "method SendEventStream not implemented")
 }
+func (UnimplementedIngestServiceServer) SendEventBatch(context.Context, *EventBatch) (*BatchAck, error) {
+	return nil, status.Error(codes.Unimplemented, "method SendEventBatch not implemented")
+}
 func (UnimplementedIngestServiceServer) mustEmbedUnimplementedIngestServiceServer() {}
 func (UnimplementedIngestServiceServer) testEmbeddedByValue()                       {}
 
@@ -134,6 +150,24 @@ func _IngestService_SendEventStream_Handler(srv interface{}, stream grpc.ServerS
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type IngestService_SendEventStreamServer = grpc.ClientStreamingServer[Event, Ack]
 
+func _IngestService_SendEventBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventBatch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServiceServer).SendEventBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestService_SendEventBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServiceServer).SendEventBatch(ctx, req.(*EventBatch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // IngestService_ServiceDesc is the grpc.ServiceDesc for IngestService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -145,6 +179,10 @@ var IngestService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendEvent",
 			Handler:    _IngestService_SendEvent_Handler,
 		},
+		{
+			MethodName: "SendEventBatch",
+			Handler:    _IngestService_SendEventBatch_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -156,9 +194,196 @@ var IngestService_ServiceDesc = grpc.ServiceDesc{
 	Metadata: "analytics.proto",
 }
 
+const (
+	SchemaService_ListSchemas_FullMethodName  = "/analytics.SchemaService/ListSchemas"
+	SchemaService_PutSchema_FullMethodName    = "/analytics.SchemaService/PutSchema"
+	SchemaService_DeleteSchema_FullMethodName = "/analytics.SchemaService/DeleteSchema"
+)
+
+// SchemaServiceClient is the client API for SchemaService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Admin service for managing event schemas. Requires an admin API key.
+type SchemaServiceClient interface {
+	ListSchemas(ctx context.Context, in *ListSchemasRequest, opts ...grpc.CallOption) (*ListSchemasResponse, error)
+	PutSchema(ctx context.Context, in *EventSchema, opts ...grpc.CallOption) (*Ack, error)
+	DeleteSchema(ctx context.Context, in *DeleteSchemaRequest, opts ...grpc.CallOption) (*Ack, error)
+}
+
+type schemaServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSchemaServiceClient(cc grpc.ClientConnInterface) SchemaServiceClient {
+	return &schemaServiceClient{cc}
+}
+
+func (c *schemaServiceClient) ListSchemas(ctx context.Context, in *ListSchemasRequest, opts ...grpc.CallOption) (*ListSchemasResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSchemasResponse)
+	err := c.cc.Invoke(ctx, SchemaService_ListSchemas_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schemaServiceClient) PutSchema(ctx context.Context, in *EventSchema, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SchemaService_PutSchema_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schemaServiceClient) DeleteSchema(ctx context.Context, in *DeleteSchemaRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SchemaService_DeleteSchema_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SchemaServiceServer is the server API for SchemaService service.
+// All implementations must embed UnimplementedSchemaServiceServer
+// for forward compatibility.
+//
+// Admin service for managing event schemas. Requires an admin API key.
+type SchemaServiceServer interface {
+	ListSchemas(context.Context, *ListSchemasRequest) (*ListSchemasResponse, error)
+	PutSchema(context.Context, *EventSchema) (*Ack, error)
+	DeleteSchema(context.Context, *DeleteSchemaRequest) (*Ack, error)
+	mustEmbedUnimplementedSchemaServiceServer()
+}
+
+// UnimplementedSchemaServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSchemaServiceServer struct{}
+
+func (UnimplementedSchemaServiceServer) ListSchemas(context.Context, *ListSchemasRequest) (*ListSchemasResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListSchemas not implemented")
+}
+func (UnimplementedSchemaServiceServer) PutSchema(context.Context, *EventSchema) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method PutSchema not implemented")
+}
+func (UnimplementedSchemaServiceServer) DeleteSchema(context.Context, *DeleteSchemaRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteSchema not implemented")
+}
+func (UnimplementedSchemaServiceServer) mustEmbedUnimplementedSchemaServiceServer() {}
+func (UnimplementedSchemaServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeSchemaServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SchemaServiceServer will
+// result in compilation errors.
+type UnsafeSchemaServiceServer interface {
+	mustEmbedUnimplementedSchemaServiceServer()
+}
+
+func RegisterSchemaServiceServer(s grpc.ServiceRegistrar, srv SchemaServiceServer) {
+	// If the following call panics, it indicates UnimplementedSchemaServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SchemaService_ServiceDesc, srv)
+}
+
+func _SchemaService_ListSchemas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSchemasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaServiceServer).ListSchemas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaService_ListSchemas_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaServiceServer).ListSchemas(ctx, req.(*ListSchemasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchemaService_PutSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventSchema)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaServiceServer).PutSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaService_PutSchema_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaServiceServer).PutSchema(ctx, req.(*EventSchema))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchemaService_DeleteSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSchemaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaServiceServer).DeleteSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaService_DeleteSchema_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaServiceServer).DeleteSchema(ctx, req.(*DeleteSchemaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SchemaService_ServiceDesc is the grpc.ServiceDesc for SchemaService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SchemaService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "analytics.SchemaService",
+	HandlerType: (*SchemaServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSchemas",
+			Handler:    _SchemaService_ListSchemas_Handler,
+		},
+		{
+			MethodName: "PutSchema",
+			Handler:    _SchemaService_PutSchema_Handler,
+		},
+		{
+			MethodName: "DeleteSchema",
+			Handler:    _SchemaService_DeleteSchema_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "analytics.proto",
+}
+
 const (
 	MetricsService_GetMetrics_FullMethodName       = "/analytics.MetricsService/GetMetrics"
 	MetricsService_SubscribeMetrics_FullMethodName = "/analytics.MetricsService/SubscribeMetrics"
+	MetricsService_GetEndpointStats_FullMethodName = "/analytics.MetricsService/GetEndpointStats"
+	MetricsService_GetLatencyStats_FullMethodName  = "/analytics.MetricsService/GetLatencyStats"
+	MetricsService_ListMetrics_FullMethodName      = "/analytics.MetricsService/ListMetrics"
+	MetricsService_TailEvents_FullMethodName       = "/analytics.MetricsService/TailEvents"
+	MetricsService_GetFunnel_FullMethodName        = "/analytics.MetricsService/GetFunnel"
 )
 
 // MetricsServiceClient is the client API for MetricsService service.
@@ -169,6 +394,11 @@ const (
 type MetricsServiceClient interface {
 	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*MetricResponse, error)
 	SubscribeMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Metric], error)
+	GetEndpointStats(ctx context.Context, in *GetEndpointStatsRequest, opts ...grpc.CallOption) (*EndpointStatsResponse, error)
+	GetLatencyStats(ctx context.Context, in *GetLatencyStatsRequest, opts ...grpc.CallOption) (*EndpointStats, error)
+	ListMetrics(ctx context.Context, in *ListMetricsRequest, opts ...grpc.CallOption) (*ListMetricsResponse, error)
+	TailEvents(ctx context.Context, in *TailEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	GetFunnel(ctx context.Context, in *GetFunnelRequest, opts ...grpc.CallOption) (*FunnelResponse, error)
 }
 
 type metricsServiceClient struct {
@@ -208,6 +438,65 @@ func (c *metricsServiceClient) SubscribeMetrics(ctx context.Context, in *GetMetr
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MetricsService_SubscribeMetricsClient = grpc.ServerStreamingClient[Metric]
 
+func (c *metricsServiceClient) GetEndpointStats(ctx context.Context, in *GetEndpointStatsRequest, opts ...grpc.CallOption) (*EndpointStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EndpointStatsResponse)
+	err := c.cc.Invoke(ctx, MetricsService_GetEndpointStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) GetLatencyStats(ctx context.Context, in *GetLatencyStatsRequest, opts ...grpc.CallOption) (*EndpointStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EndpointStats)
+	err := c.cc.Invoke(ctx, MetricsService_GetLatencyStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) ListMetrics(ctx context.Context, in *ListMetricsRequest, opts ...grpc.CallOption) (*ListMetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMetricsResponse)
+	err := c.cc.Invoke(ctx, MetricsService_ListMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) TailEvents(ctx context.Context, in *TailEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MetricsService_ServiceDesc.Streams[1], MetricsService_TailEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TailEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MetricsService_TailEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *metricsServiceClient) GetFunnel(ctx context.Context, in *GetFunnelRequest, opts ...grpc.CallOption) (*FunnelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FunnelResponse)
+	err := c.cc.Invoke(ctx, MetricsService_GetFunnel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricsServiceServer is the server API for MetricsService service.
 // All implementations must embed UnimplementedMetricsServiceServer
 // for forward compatibility.
@@ -216,6 +505,11 @@ type MetricsService_SubscribeMetricsClient = grpc.ServerStreamingClient[Metric]
 type MetricsServiceServer interface {
 	GetMetrics(context.Context, *GetMetricsRequest) (*MetricResponse, error)
 	SubscribeMetrics(*GetMetricsRequest, grpc.ServerStreamingServer[Metric]) error
+	GetEndpointStats(context.Context, *GetEndpointStatsRequest) (*EndpointStatsResponse, error)
+	GetLatencyStats(context.Context, *GetLatencyStatsRequest) (*EndpointStats, error)
+	ListMetrics(context.Context, *ListMetricsRequest) (*ListMetricsResponse, error)
+	TailEvents(*TailEventsRequest, grpc.ServerStreamingServer[Event]) error
+	GetFunnel(context.Context, *GetFunnelRequest) (*FunnelResponse, error)
 	mustEmbedUnimplementedMetricsServiceServer()
 }
 
@@ -232,6 +526,21 @@ func (UnimplementedMetricsServiceServer) GetMetrics(context.Context, *GetMetrics
 func (UnimplementedMetricsServiceServer) SubscribeMetrics(*GetMetricsRequest, grpc.ServerStreamingServer[Metric]) error {
 	return status.Error(codes.Unimplemented, "method SubscribeMetrics not implemented")
 }
+func (UnimplementedMetricsServiceServer) GetEndpointStats(context.Context, *GetEndpointStatsRequest) (*EndpointStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetEndpointStats not implemented")
+}
+func (UnimplementedMetricsServiceServer) GetLatencyStats(context.Context, *GetLatencyStatsRequest) (*EndpointStats, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetLatencyStats not implemented")
+}
+func (UnimplementedMetricsServiceServer) ListMetrics(context.Context, *ListMetricsRequest) (*ListMetricsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListMetrics not implemented")
+}
+func (UnimplementedMetricsServiceServer) TailEvents(*TailEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method TailEvents not implemented")
+}
+func (UnimplementedMetricsServiceServer) GetFunnel(context.Context, *GetFunnelRequest) (*FunnelResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetFunnel not implemented")
+}
 func (UnimplementedMetricsServiceServer) mustEmbedUnimplementedMetricsServiceServer() {}
 func (UnimplementedMetricsServiceServer) testEmbeddedByValue()                        {}
 
@@ -282,6 +591,89 @@ func _MetricsService_SubscribeMetrics_Handler(srv interface{}, stream grpc.Serve
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MetricsService_SubscribeMetricsServer = grpc.ServerStreamingServer[Metric]
 
+func _MetricsService_GetEndpointStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEndpointStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetEndpointStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetEndpointStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetEndpointStats(ctx, req.(*GetEndpointStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_GetLatencyStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLatencyStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetLatencyStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetLatencyStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetLatencyStats(ctx, req.(*GetLatencyStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_ListMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).ListMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_ListMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).ListMetrics(ctx, req.(*ListMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_TailEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetricsServiceServer).TailEvents(m, &grpc.GenericServerStream[TailEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MetricsService_TailEventsServer = grpc.ServerStreamingServer[Event]
+
+func _MetricsService_GetFunnel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFunnelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetFunnel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetFunnel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetFunnel(ctx, req.(*GetFunnelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetricsService_ServiceDesc is the grpc.ServiceDesc for MetricsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -293,6 +685,22 @@ var MetricsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMetrics",
 			Handler:    _MetricsService_GetMetrics_Handler,
 		},
+		{
+			MethodName: "GetEndpointStats",
+			Handler:    _MetricsService_GetEndpointStats_Handler,
+		},
+		{
+			MethodName: "GetLatencyStats",
+			Handler:    _MetricsService_GetLatencyStats_Handler,
+		},
+		{
+			MethodName: "ListMetrics",
+			Handler:    _MetricsService_ListMetrics_Handler,
+		},
+		{
+			MethodName: "GetFunnel",
+			Handler:    _MetricsService_GetFunnel_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -300,6 +708,11 @@ var MetricsService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _MetricsService_SubscribeMetrics_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "TailEvents",
+			Handler:       _MetricsService_TailEvents_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "analytics.proto",
 }
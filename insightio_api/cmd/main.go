@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,15 +23,49 @@ const (
 	httpPort     = ":8080"
 	grpcTarget   = "localhost:50051"
 	ingestPath   = "/v1/event"
+	batchPath    = "/v1/events"
+	maxBatchSize = 1000
 	apiKeyHeader = "x-api-key"
+
+	// carries the client's address to the backend's peer_address processor
+	forwardedForHeader = "x-forwarded-for"
+
+	// a retried request carries the same key, so the events it resends are
+	// recognized as duplicates
+	idempotencyKeyHeader = "Idempotency-Key"
 )
 
 // mirrors the structure recieved from the client via http/json
 type IngestPayload struct {
+	Id       string            `json:"id,omitempty"` // client event id, reused on retries
 	Type     string            `json:"type"`
 	UserId   string            `json:"user_id,omitempty"`
 	Metadata map[string]string `json:"metadata,omitempty"`
 	Value    float64           `json:"value,omitempty"`
+
+	// when the event happened, RFC 3339; the time it reached the gateway
+	// when unset
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// batch of events recieved in one http/json request
+type BatchPayload struct {
+	Events []IngestPayload `json:"events"`
+}
+
+// per-event result returned for a batch
+type BatchEventError struct {
+	Index     int    `json:"index"`
+	Reason    string `json:"reason"`
+	Retryable bool   `json:"retryable"`
+}
+
+type BatchResponse struct {
+	Accepted   int               `json:"accepted"`
+	Rejected   int               `json:"rejected"`
+	Duplicates int               `json:"duplicates"`
+	Filtered   int               `json:"filtered"`
+	Errors     []BatchEventError `json:"errors,omitempty"`
 }
 
 var gRPCClient pb.IngestServiceClient
@@ -46,6 +83,7 @@ func main() {
 
 	//http router
 	http.HandleFunc(ingestPath, ingestHandler)
+	http.HandleFunc(batchPath, batchHandler)
 
 	log.Printf("Starting API Gateway on %s, proxying to %s", httpPort, grpcTarget)
 	if err := http.ListenAndServe(httpPort, nil); err != nil {
@@ -76,13 +114,17 @@ func ingestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 1. Transcode and Enrich to Protobuf Message
+	id := payload.Id
+	if id == "" {
+		id = r.Header.Get(idempotencyKeyHeader)
+	}
 	event := &pb.Event{
-		Id:        uuid.New().String(),
+		Id:        eventID(id),
 		Type:      payload.Type,
 		Value:     payload.Value,
 		UserId:    payload.UserId,
 		Metadata:  payload.Metadata,
-		Timestamp: timestamppb.Now(),
+		Timestamp: eventTime(payload.Timestamp, timestamppb.Now()),
 	}
 
 	// 2. Extract API Key and propagate it via gRPC Metadata
@@ -95,7 +137,7 @@ func ingestHandler(w http.ResponseWriter, r *http.Request) {
 	// Use request context instead of Background() for proper cancellation
 	ctx := r.Context()
 	// Append the API Key to the gRPC context for the server's Interceptor
-	ctx = metadata.AppendToOutgoingContext(ctx, apiKeyHeader, apiKey)
+	ctx = metadata.AppendToOutgoingContext(ctx, apiKeyHeader, apiKey, forwardedForHeader, forwardedFor(r))
 
 	// 3. Call the gRPC Backend (Unary RPC)
 	gRPCContext, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
@@ -128,3 +170,124 @@ func ingestHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to encode response: %v", err)
 	}
 }
+
+// batchHandler proxies a batch of events to gRPC and reports which of them
+// were rejected, by their position in the request.
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Limit request body size to prevent DoS (10MB max)
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+
+	var payload BatchPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Events) == 0 {
+		http.Error(w, "Field 'events' must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Events) > maxBatchSize {
+		http.Error(w, fmt.Sprintf("At most %d events per batch", maxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	apiKey := r.Header.Get(apiKeyHeader)
+	if apiKey == "" {
+		http.Error(w, apiKeyHeader+" header missing", http.StatusUnauthorized)
+		return
+	}
+
+	// Invalid events are left to the backend so every rejection is reported
+	// the same way, by index
+	batch := &pb.EventBatch{Events: make([]*pb.Event, len(payload.Events))}
+	now := timestamppb.Now()
+	key := r.Header.Get(idempotencyKeyHeader)
+	for i, p := range payload.Events {
+		id := p.Id
+		if id == "" && key != "" {
+			id = fmt.Sprintf("%s/%d", key, i)
+		}
+		batch.Events[i] = &pb.Event{
+			Id:        eventID(id),
+			Type:      p.Type,
+			Value:     p.Value,
+			UserId:    p.UserId,
+			Metadata:  p.Metadata,
+			Timestamp: eventTime(p.Timestamp, now),
+		}
+	}
+
+	ctx := metadata.AppendToOutgoingContext(r.Context(), apiKeyHeader, apiKey, forwardedForHeader, forwardedFor(r))
+	gRPCContext, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	resp, err := gRPCClient.SendEventBatch(gRPCContext, batch)
+	if err != nil {
+		log.Printf("gRPC SendEventBatch failed: %v", err)
+		http.Error(w, "Backend service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	out := BatchResponse{
+		Accepted:   int(resp.Accepted),
+		Rejected:   int(resp.Rejected),
+		Duplicates: int(resp.Duplicates),
+		Filtered:   int(resp.Filtered),
+	}
+	for _, e := range resp.Errors {
+		out.Errors = append(out.Errors, BatchEventError{
+			Index:     int(e.Index),
+			Reason:    e.Reason,
+			Retryable: e.Retryable,
+		})
+	}
+
+	// HTTP 202 if every event was accepted, 207 if the client has to look
+	// at the per-event results
+	code := http.StatusAccepted
+	if out.Rejected > 0 {
+		code = http.StatusMultiStatus
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// eventID returns the id the client gave an event, or a new one when it gave
+// none. Only client ids let the backend recognize a retried event.
+func eventID(id string) string {
+	if id != "" {
+		return id
+	}
+	return uuid.New().String()
+}
+
+// eventTime returns the time the client gave an event, or now when it gave
+// none. The backend decides whether a client time is too late or too far
+// ahead.
+func eventTime(t *time.Time, now *timestamppb.Timestamp) *timestamppb.Timestamp {
+	if t == nil {
+		return now
+	}
+	return timestamppb.New(*t)
+}
+
+// forwardedFor returns the request's X-Forwarded-For chain with the address
+// the gateway received it from appended.
+func forwardedFor(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if prior := r.Header.Values(forwardedForHeader); len(prior) > 0 {
+		return strings.Join(prior, ", ") + ", " + host
+	}
+	return host
+}